@@ -0,0 +1,219 @@
+//go:build windows
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DedupIndex maps a content hash to every path sharing it. Only hashes with
+// two or more paths represent an actual duplicate; buildDedupIndex never
+// returns singleton groups.
+type DedupIndex map[string][]string
+
+// dedupWorkers bounds how many files are hashed concurrently.
+const dedupWorkers = 8
+
+// fileStat is a flattened, recursive listing entry used for dedup scanning,
+// where (unlike scanDirectory) every file needs to be visited individually
+// rather than folded into a directory total.
+type fileStat struct {
+	Path string
+	Size int64
+}
+
+// walkFiles recursively lists every regular file under root via fsys.
+func walkFiles(fsys FS, root string) ([]fileStat, error) {
+	var out []fileStat
+	entries, err := fsys.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if skipPatterns[e.Name] {
+			continue
+		}
+		path := filepath.Join(root, e.Name)
+		if e.IsDir {
+			// A junction/symlink pointing back at an ancestor would
+			// otherwise make this recurse forever.
+			if isSymlinkedDir(fsys, path) {
+				continue
+			}
+			children, err := walkFiles(fsys, path)
+			if err != nil {
+				continue // an unreadable subtree shouldn't abort the whole scan
+			}
+			out = append(out, children...)
+			continue
+		}
+		meta, err := fsys.Stat(path)
+		if err != nil {
+			continue
+		}
+		out = append(out, fileStat{Path: path, Size: meta.Size})
+	}
+	return out, nil
+}
+
+// buildDedupIndex finds duplicate files under root using the same two-phase
+// approach restic and other backup tools use: group by size first (cheap,
+// no I/O beyond the stats walkFiles already did), then hash only the files
+// that collide on size, in parallel, consulting cache to skip anything
+// that's unchanged since the last scan.
+func buildDedupIndex(fsys FS, root string, cache *hashCache) (DedupIndex, error) {
+	files, err := walkFiles(fsys, root)
+	if err != nil {
+		return nil, err
+	}
+
+	bySize := make(map[int64][]fileStat)
+	for _, f := range files {
+		bySize[f.Size] = append(bySize[f.Size], f)
+	}
+
+	var candidates []fileStat
+	for size, group := range bySize {
+		if size == 0 || len(group) < 2 {
+			continue // nothing to dedup: unique size, or an empty file
+		}
+		candidates = append(candidates, group...)
+	}
+
+	type hashResult struct {
+		path string
+		hash string
+		err  error
+	}
+
+	results := make(chan hashResult, len(candidates))
+	sem := make(chan struct{}, dedupWorkers)
+	var wg sync.WaitGroup
+
+	for _, f := range candidates {
+		f := f
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			hash, err := hashFile(fsys, cache, f.Path, f.Size)
+			results <- hashResult{path: f.Path, hash: hash, err: err}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	idx := make(DedupIndex)
+	for r := range results {
+		if r.err != nil {
+			continue // unreadable file: skip it rather than fail the whole scan
+		}
+		idx[r.hash] = append(idx[r.hash], r.path)
+	}
+
+	// Same-size files can still hash differently, so prune groups that
+	// collapsed back down to a single member once content is considered.
+	for hash, paths := range idx {
+		if len(paths) < 2 {
+			delete(idx, hash)
+			continue
+		}
+		sort.Strings(paths)
+	}
+	return idx, nil
+}
+
+// hashFile returns the SHA-256 of path's contents, consulting cache (keyed
+// by path+mtime+size) first so unchanged files aren't re-read.
+func hashFile(fsys FS, cache *hashCache, path string, size int64) (string, error) {
+	meta, err := fsys.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if cache != nil {
+		if hash, ok := cache.Get(path, meta.ModTime, size); ok {
+			return hash, nil
+		}
+	}
+
+	f, err := fsys.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	if cache != nil {
+		cache.Put(path, meta.ModTime, size, hash)
+	}
+	return hash, nil
+}
+
+// dedupReclaimable returns the total bytes reclaimable if every duplicate
+// group kept only one copy: sum(size * (count - 1)) across all groups.
+func dedupReclaimable(fsys FS, idx DedupIndex) int64 {
+	var total int64
+	for _, paths := range idx {
+		if len(paths) < 2 {
+			continue
+		}
+		meta, err := fsys.Stat(paths[0])
+		if err != nil {
+			continue
+		}
+		total += meta.Size * int64(len(paths)-1)
+	}
+	return total
+}
+
+// selectAllButNewest marks every path in each duplicate group for deletion
+// except the most recently modified one, writing into selected (normally
+// model.multiSelected).
+func selectAllButNewest(fsys FS, idx DedupIndex, selected map[string]bool) error {
+	for _, paths := range idx {
+		if len(paths) < 2 {
+			continue
+		}
+		newest := paths[0]
+		newestTime, err := statModTime(fsys, newest)
+		if err != nil {
+			return err
+		}
+		for _, p := range paths[1:] {
+			t, err := statModTime(fsys, p)
+			if err != nil {
+				return err
+			}
+			if t.After(newestTime) {
+				newest, newestTime = p, t
+			}
+		}
+		for _, p := range paths {
+			selected[p] = p != newest
+		}
+	}
+	return nil
+}
+
+func statModTime(fsys FS, path string) (time.Time, error) {
+	meta, err := fsys.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return meta.ModTime, nil
+}
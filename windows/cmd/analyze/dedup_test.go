@@ -0,0 +1,133 @@
+//go:build windows
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildDedupIndexIgnoresSameSizeDifferentContent(t *testing.T) {
+	fsys := newFakeFS()
+	fsys.AddFileWithContent(`C:\data\a.bin`, []byte("aaaaaaaaaa"))
+	fsys.AddFileWithContent(`C:\data\b.bin`, []byte("bbbbbbbbbb")) // same size, different bytes
+	fsys.AddFileWithContent(`C:\data\c.bin`, []byte("aaaaaaaaaa")) // true duplicate of a.bin
+
+	idx, err := buildDedupIndex(fsys, `C:\data`, nil)
+	if err != nil {
+		t.Fatalf("buildDedupIndex: %v", err)
+	}
+
+	var found []string
+	for _, paths := range idx {
+		found = append(found, paths...)
+	}
+	if len(found) != 2 {
+		t.Fatalf("expected exactly 2 paths across all dedup groups (a.bin + c.bin), got %v", found)
+	}
+	for _, p := range found {
+		if p == `C:\data\b.bin` {
+			t.Error("b.bin has different content from a.bin/c.bin despite matching size; it must not appear in a dedup group")
+		}
+	}
+	for hash, paths := range idx {
+		if len(paths) < 2 {
+			t.Errorf("dedup index group %s has %d member(s), want >= 2", hash, len(paths))
+		}
+	}
+}
+
+func TestSelectAllButNewestKeepsMostRecent(t *testing.T) {
+	fsys := newFakeFS()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys.AddFileWithContent(`C:\data\old.bin`, []byte("duplicate-content"))
+	fsys.AddFileWithContent(`C:\data\mid.bin`, []byte("duplicate-content"))
+	fsys.AddFileWithContent(`C:\data\new.bin`, []byte("duplicate-content"))
+	fsys.SetModTime(`C:\data\old.bin`, base)
+	fsys.SetModTime(`C:\data\mid.bin`, base.Add(time.Hour))
+	fsys.SetModTime(`C:\data\new.bin`, base.Add(2*time.Hour))
+
+	idx, err := buildDedupIndex(fsys, `C:\data`, nil)
+	if err != nil {
+		t.Fatalf("buildDedupIndex: %v", err)
+	}
+	if len(idx) != 1 {
+		t.Fatalf("expected exactly one dedup group, got %d", len(idx))
+	}
+
+	selected := make(map[string]bool)
+	if err := selectAllButNewest(fsys, idx, selected); err != nil {
+		t.Fatalf("selectAllButNewest: %v", err)
+	}
+
+	if selected[`C:\data\new.bin`] {
+		t.Error("newest file must not be selected for deletion")
+	}
+	if !selected[`C:\data\old.bin`] || !selected[`C:\data\mid.bin`] {
+		t.Error("every file but the newest must be selected for deletion")
+	}
+}
+
+// TestWalkFilesSkipsSymlinkLoop mirrors
+// TestCalculateDirSizeSkipsSymlinkLoop for the dedup scanner's own
+// recursive walk: a junction pointing back at an ancestor must not send
+// walkFiles into infinite recursion.
+func TestWalkFilesSkipsSymlinkLoop(t *testing.T) {
+	fsys := newFakeFS()
+	fsys.AddFile(`C:\mole_walk_loop_test\real.txt`, 100)
+
+	dir, err := fsys.lookup(`C:\mole_walk_loop_test`)
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	dir.children["loop"] = &fakeNode{isDir: true, isSymlink: true, children: dir.children}
+
+	type result struct {
+		files []fileStat
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		files, err := walkFiles(fsys, `C:\mole_walk_loop_test`)
+		done <- result{files, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatalf("walkFiles: %v", r.err)
+		}
+		if len(r.files) != 1 || r.files[0].Path != `C:\mole_walk_loop_test\real.txt` {
+			t.Errorf("walkFiles() = %v, want just real.txt (symlinked loop must not be walked)", r.files)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("walkFiles() did not return — it recursed into the symlink loop")
+	}
+}
+
+func TestHashCacheAvoidsRehashingUnchangedFile(t *testing.T) {
+	fsys := newFakeFS()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys.AddFileWithContent(`C:\data\a.bin`, []byte("content"))
+	fsys.SetModTime(`C:\data\a.bin`, base)
+
+	cache := newHashCache(t.TempDir()+`\cache.json`, 10)
+
+	first, err := hashFile(fsys, cache, `C:\data\a.bin`, 7)
+	if err != nil {
+		t.Fatalf("hashFile: %v", err)
+	}
+
+	// Mutate the underlying content without changing size/mtime: a correct
+	// cache should still return the stale hash it computed above.
+	fsys.AddFileWithContent(`C:\data\a.bin`, []byte("CONTENT"))
+	fsys.SetModTime(`C:\data\a.bin`, base)
+
+	second, err := hashFile(fsys, cache, `C:\data\a.bin`, 7)
+	if err != nil {
+		t.Fatalf("hashFile: %v", err)
+	}
+	if first != second {
+		t.Error("expected cached hash to be reused for an unchanged (path, mtime, size) key")
+	}
+}
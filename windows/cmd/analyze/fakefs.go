@@ -0,0 +1,223 @@
+//go:build windows
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// fakeNode is one entry in a fakeFS tree.
+type fakeNode struct {
+	isDir         bool
+	size          int64
+	allocatedSize int64
+	isSparse      bool
+	isSymlink     bool
+	symlinkTarget string
+	permDenied    bool
+	ioDelay       time.Duration
+	modTime       time.Time
+	// content is optional: only tests that hash file data (dedup scanning)
+	// need to set it. Size-only files read back as that many zero bytes.
+	content  []byte
+	children map[string]*fakeNode
+}
+
+// fakeFS is an in-memory filesystem used by tests so they can build
+// multi-GB synthetic trees, permission-denied paths, and slow-I/O
+// simulations without touching disk. Paths are addressed the same way
+// osFS addresses them: "C:\\Windows\\System32", split on \ or /.
+type fakeFS struct {
+	root *fakeNode
+	// diskFree/diskTotal are returned for every DiskFree call; tests that
+	// care about a specific volume can set these directly.
+	diskFree, diskTotal uint64
+}
+
+// newFakeFS returns an empty fakeFS rooted at a single synthetic directory.
+func newFakeFS() *fakeFS {
+	return &fakeFS{root: &fakeNode{isDir: true, children: make(map[string]*fakeNode)}}
+}
+
+func splitFakePath(path string) []string {
+	parts := strings.FieldsFunc(path, func(r rune) bool { return r == '\\' || r == '/' })
+	return parts
+}
+
+// AddFile registers a file of the given logical size at path, creating any
+// missing parent directories.
+func (f *fakeFS) AddFile(path string, size int64) {
+	node := f.mkdirAll(path)
+	node.isDir = false
+	node.size = size
+	node.allocatedSize = size
+}
+
+// AddSparseFile registers a file whose logical size is larger than what it
+// actually occupies on disk, for exercising sparse-savings logic.
+func (f *fakeFS) AddSparseFile(path string, logicalSize, allocatedSize int64) {
+	node := f.mkdirAll(path)
+	node.isDir = false
+	node.size = logicalSize
+	node.allocatedSize = allocatedSize
+	node.isSparse = true
+}
+
+// AddFileWithContent registers a file whose bytes are content, so Open and
+// dedup hashing see real, distinguishable data instead of zero-filled bytes.
+func (f *fakeFS) AddFileWithContent(path string, content []byte) {
+	node := f.mkdirAll(path)
+	node.isDir = false
+	node.size = int64(len(content))
+	node.allocatedSize = node.size
+	node.content = content
+}
+
+// SetModTime records the modification time reported for path's Stat/Lstat,
+// used by dedup's "keep newest" selection.
+func (f *fakeFS) SetModTime(path string, t time.Time) {
+	f.mkdirAll(path).modTime = t
+}
+
+// AddDir registers an empty directory at path.
+func (f *fakeFS) AddDir(path string) {
+	node := f.mkdirAll(path)
+	node.isDir = true
+	if node.children == nil {
+		node.children = make(map[string]*fakeNode)
+	}
+}
+
+// AddSymlink registers a symlink at path pointing at target. It is not
+// followed by ReadDir/Stat — callers wanting the link target use Lstat.
+func (f *fakeFS) AddSymlink(path, target string) {
+	node := f.mkdirAll(path)
+	node.isSymlink = true
+	node.symlinkTarget = target
+}
+
+// SetPermissionDenied makes every future ReadDir/Stat against path fail with
+// a permission error, simulating protected system directories.
+func (f *fakeFS) SetPermissionDenied(path string) {
+	f.mkdirAll(path).permDenied = true
+}
+
+// SetIODelay makes operations against path sleep for d first, simulating a
+// slow network share or a spun-down disk.
+func (f *fakeFS) SetIODelay(path string, d time.Duration) {
+	f.mkdirAll(path).ioDelay = d
+}
+
+// mkdirAll walks path from the root, creating directory nodes for any
+// missing segment, and returns the (possibly just-created) leaf node.
+func (f *fakeFS) mkdirAll(path string) *fakeNode {
+	cur := f.root
+	for _, part := range splitFakePath(path) {
+		if cur.children == nil {
+			cur.children = make(map[string]*fakeNode)
+		}
+		next, ok := cur.children[part]
+		if !ok {
+			next = &fakeNode{isDir: true, children: make(map[string]*fakeNode)}
+			cur.children[part] = next
+		}
+		cur = next
+	}
+	return cur
+}
+
+func (f *fakeFS) lookup(path string) (*fakeNode, error) {
+	cur := f.root
+	for _, part := range splitFakePath(path) {
+		if cur.children == nil {
+			return nil, fmt.Errorf("%s: %w", path, os.ErrNotExist)
+		}
+		next, ok := cur.children[part]
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", path, os.ErrNotExist)
+		}
+		cur = next
+	}
+	return cur, nil
+}
+
+func (f *fakeFS) ReadDir(path string) ([]DirEntry, error) {
+	node, err := f.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	if node.ioDelay > 0 {
+		time.Sleep(node.ioDelay)
+	}
+	if node.permDenied {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: os.ErrPermission}
+	}
+	entries := make([]DirEntry, 0, len(node.children))
+	for name, child := range node.children {
+		entries = append(entries, DirEntry{Name: name, IsDir: child.isDir})
+	}
+	return entries, nil
+}
+
+func (f *fakeFS) Stat(path string) (FileMeta, error) {
+	node, err := f.lookup(path)
+	if err != nil {
+		return FileMeta{}, err
+	}
+	if node.ioDelay > 0 {
+		time.Sleep(node.ioDelay)
+	}
+	if node.permDenied {
+		return FileMeta{}, &os.PathError{Op: "stat", Path: path, Err: os.ErrPermission}
+	}
+	allocated := node.allocatedSize
+	if node.isDir && !node.isSymlink {
+		allocated = calculateDirAllocatedSize(f, path)
+	}
+	return FileMeta{
+		Size:          node.size,
+		IsDir:         node.isDir,
+		IsSymlink:     node.isSymlink,
+		AllocatedSize: allocated,
+		IsSparse:      node.isSparse,
+		ModTime:       node.modTime,
+	}, nil
+}
+
+func (f *fakeFS) Lstat(path string) (FileMeta, error) {
+	return f.Stat(path)
+}
+
+func (f *fakeFS) Open(path string) (io.ReadCloser, error) {
+	node, err := f.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	if node.permDenied {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrPermission}
+	}
+	if node.content != nil {
+		return io.NopCloser(bytes.NewReader(node.content)), nil
+	}
+	return io.NopCloser(io.LimitReader(zeroReader{}, node.size)), nil
+}
+
+// zeroReader produces an endless stream of zero bytes, used to back
+// size-only fake files that nobody needs real content from.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func (f *fakeFS) DiskFree(path string) (free, total uint64, err error) {
+	return f.diskFree, f.diskTotal, nil
+}
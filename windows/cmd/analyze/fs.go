@@ -0,0 +1,39 @@
+//go:build windows
+
+package main
+
+import (
+	"io"
+	"time"
+)
+
+// FS abstracts the filesystem operations scanDirectory and calculateDirSize
+// need. osFS hits the real Windows filesystem; fakeFS is an in-memory
+// stand-in used by tests so they can exercise multi-GB synthetic trees,
+// permission errors, and paths like C:\Windows without ever touching disk.
+type FS interface {
+	ReadDir(path string) ([]DirEntry, error)
+	Stat(path string) (FileMeta, error)
+	Lstat(path string) (FileMeta, error)
+	DiskFree(path string) (free, total uint64, err error)
+	// Open returns the contents of the file at path, for dedup hashing.
+	Open(path string) (io.ReadCloser, error)
+}
+
+// DirEntry is the subset of os.DirEntry scanDirectory needs.
+type DirEntry struct {
+	Name  string
+	IsDir bool
+}
+
+// FileMeta is the subset of file metadata the scanner cares about. Size is
+// always the logical size; AllocatedSize/IsSparse mirror the fields added to
+// FileInfo for sparse-file reporting.
+type FileMeta struct {
+	Size          int64
+	IsDir         bool
+	IsSymlink     bool
+	AllocatedSize int64
+	IsSparse      bool
+	ModTime       time.Time
+}
@@ -0,0 +1,134 @@
+//go:build windows
+
+package main
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// hashCacheEntry is one persisted (path, mtime, size) -> hash mapping.
+type hashCacheEntry struct {
+	Key  string `json:"key"`
+	Hash string `json:"hash"`
+}
+
+// hashCache is a bounded LRU of file-content hashes keyed by
+// (path, mtime, size), so a dedup re-scan skips rehashing files that
+// haven't changed since last time. It's persisted to disk as JSON between
+// runs.
+type hashCache struct {
+	mu       sync.Mutex
+	path     string
+	capacity int
+	order    *list.List               // front = most recently used
+	elems    map[string]*list.Element // key -> element holding hashCacheEntry
+}
+
+const defaultHashCacheCapacity = 100_000
+
+// newHashCache loads path if it exists, or starts empty. A missing or
+// corrupt cache file is not an error: dedup scanning just runs cold.
+func newHashCache(path string, capacity int) *hashCache {
+	if capacity <= 0 {
+		capacity = defaultHashCacheCapacity
+	}
+	c := &hashCache{
+		path:     path,
+		capacity: capacity,
+		order:    list.New(),
+		elems:    make(map[string]*list.Element),
+	}
+	c.load()
+	return c
+}
+
+func hashCacheKey(path string, modTime time.Time, size int64) string {
+	return fmt.Sprintf("%s|%d|%d", path, modTime.UnixNano(), size)
+}
+
+func (c *hashCache) load() {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return
+	}
+	var entries []hashCacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return
+	}
+	for _, e := range entries {
+		el := c.order.PushBack(e)
+		c.elems[e.Key] = el
+	}
+	c.evictLocked()
+}
+
+// Get returns the cached hash for (path, modTime, size), if present, and
+// marks it most-recently-used.
+func (c *hashCache) Get(path string, modTime time.Time, size int64) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := hashCacheKey(path, modTime, size)
+	el, ok := c.elems[key]
+	if !ok {
+		return "", false
+	}
+	entry := el.Value.(hashCacheEntry)
+	c.order.MoveToFront(el)
+	return entry.Hash, true
+}
+
+// Put records hash for (path, modTime, size), evicting the least-recently
+// used entry if the cache is at capacity.
+func (c *hashCache) Put(path string, modTime time.Time, size int64, hash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := hashCacheKey(path, modTime, size)
+	if el, ok := c.elems[key]; ok {
+		c.order.MoveToFront(el)
+		el.Value = hashCacheEntry{Key: key, Hash: hash}
+		return
+	}
+	el := c.order.PushFront(hashCacheEntry{Key: key, Hash: hash})
+	c.elems[key] = el
+	c.evictLocked()
+}
+
+func (c *hashCache) evictLocked() {
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		if oldest == nil {
+			return
+		}
+		entry := oldest.Value.(hashCacheEntry)
+		delete(c.elems, entry.Key)
+		c.order.Remove(oldest)
+	}
+}
+
+// Save persists the cache to disk as JSON, most-recently-used first.
+func (c *hashCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make([]hashCacheEntry, 0, c.order.Len())
+	for el := c.order.Front(); el != nil; el = el.Next() {
+		entries = append(entries, el.Value.(hashCacheEntry))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0o644)
+}
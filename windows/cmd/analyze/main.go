@@ -0,0 +1,483 @@
+//go:build windows
+
+// Command analyze is an interactive disk-usage browser: point it at a
+// directory and it walks the tree, flags folders matching common
+// build-artifact patterns, and lets you drill in and delete what you find.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// cleanablePatterns are directory names that are almost always safe to
+// delete and regenerate (package manager caches, build output, venvs).
+var cleanablePatterns = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	".venv":        true,
+	"venv":         true,
+	"__pycache__":  true,
+	"target":       true,
+	"build":        true,
+	"dist":         true,
+}
+
+// skipPatterns are paths we never descend into: system directories where a
+// full recursive size scan is slow, risky, or both.
+var skipPatterns = map[string]bool{
+	"$Recycle.Bin":              true,
+	"System Volume Information": true,
+	"Windows":                   true,
+	"Program Files":             true,
+}
+
+// FileInfo describes one entry (file or directory) under the scanned path.
+type FileInfo struct {
+	Name string
+	Path string
+	Size int64
+	// AllocatedSize is how many bytes this entry actually occupies on disk.
+	// It differs from Size for sparse files, where large logical ranges
+	// were never written and cost nothing.
+	AllocatedSize int64
+	IsDir         bool
+	IsSparse      bool
+	Cleanable     bool
+}
+
+// SparseSavings is how many bytes are reclaimable because they're logical
+// size but never-allocated hole, i.e. Size - AllocatedSize.
+func (f FileInfo) SparseSavings() int64 {
+	if f.Size <= f.AllocatedSize {
+		return 0
+	}
+	return f.Size - f.AllocatedSize
+}
+
+// sortMode controls the ordering entries are presented in.
+type sortMode int
+
+const (
+	sortBySize sortMode = iota
+	sortByName
+	sortByWasted
+)
+
+// viewMode selects which screen the analyzer is currently showing.
+type viewMode int
+
+const (
+	viewEntries viewMode = iota
+	viewDedup
+)
+
+// model is the bubbletea model backing the analyzer TUI.
+type model struct {
+	path     string
+	scanning bool
+	entries  []FileInfo
+	// largeFiles lists individual files above a size threshold, surfaced
+	// separately from directory entries since they're often the real offender.
+	largeFiles []FileInfo
+	totalSize  int64
+	cursor     int
+	sortMode   sortMode
+	view       viewMode
+	// multiSelected holds the set of paths the user has tagged across
+	// multiple screens, e.g. for a batch delete.
+	multiSelected map[string]bool
+	// cache memoizes calculateDirSize results for directories already walked
+	// this session, so re-rendering a sorted view doesn't re-stat the tree.
+	cache map[string]int64
+	fsys  FS
+	// dedupIndex holds the result of the most recent duplicate scan,
+	// hash -> every path sharing it.
+	dedupIndex   DedupIndex
+	dedupScanned bool
+	hashCache    *hashCache
+	err          error
+	// statusMsg is a one-line result from the last user-triggered action
+	// (e.g. compacting a sparse file), shown below the entry list until the
+	// next action replaces it.
+	statusMsg string
+}
+
+// newModel returns a fresh analyzer model that immediately starts scanning
+// path against the real filesystem.
+func newModel(path string) model {
+	return newModelWithFS(osFS{}, path)
+}
+
+// newModelWithFS is newModel with the filesystem made explicit, so tests can
+// pass a fakeFS instead of touching disk.
+func newModelWithFS(fsys FS, path string) model {
+	return model{
+		path:          path,
+		scanning:      true,
+		multiSelected: make(map[string]bool),
+		cache:         make(map[string]int64),
+		fsys:          fsys,
+		hashCache:     newHashCache(defaultHashCachePath(), defaultHashCacheCapacity),
+	}
+}
+
+// defaultHashCachePath is where the dedup hash LRU is persisted between runs.
+func defaultHashCachePath() string {
+	dir := os.Getenv("LOCALAPPDATA")
+	if dir == "" {
+		dir = "."
+	}
+	return filepath.Join(dir, "Mole", "dedup_hash_cache.json")
+}
+
+// formatBytes renders a byte count using the largest whole unit that keeps
+// at least one significant digit before the decimal point.
+func formatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	units := []string{"KB", "MB", "GB", "TB", "PB"}
+	return fmt.Sprintf("%.1f %s", float64(b)/float64(div), units[exp])
+}
+
+// truncatePath shortens a path to maxLen characters, keeping the tail (the
+// part closest to the file itself is usually the part worth reading) and
+// prefixing "...".
+func truncatePath(path string, maxLen int) string {
+	if len(path) <= maxLen {
+		return path
+	}
+	if maxLen <= 3 {
+		return path[:maxLen]
+	}
+	return "..." + path[len(path)-(maxLen-3):]
+}
+
+// calculateDirSize walks dir via fsys and sums the logical size of every
+// regular file under it, skipping entries it can't stat rather than failing
+// the whole walk.
+func calculateDirSize(fsys FS, dir string) int64 {
+	var total int64
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return total
+	}
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name)
+		if e.IsDir {
+			if isSymlinkedDir(fsys, path) {
+				continue
+			}
+			total += calculateDirSize(fsys, path)
+			continue
+		}
+		meta, err := fsys.Stat(path)
+		if err != nil {
+			continue
+		}
+		total += meta.Size
+	}
+	return total
+}
+
+// isSymlinkedDir reports whether path is a directory reached through a
+// symlink or junction. Callers must check this before recursing into a
+// subdirectory: Stat follows reparse points, so a junction pointing back at
+// an ancestor would otherwise recurse forever.
+func isSymlinkedDir(fsys FS, path string) bool {
+	meta, err := fsys.Lstat(path)
+	return err == nil && meta.IsSymlink
+}
+
+// calculateDirAllocatedSize walks dir via fsys and sums the actual on-disk
+// allocated size of every regular file under it, mirroring calculateDirSize's
+// recursion. FS.Stat implementations use this for directories, since a
+// directory's own allocated size is meaningless — without it, AllocatedSize
+// defaults to 0 and SparseSavings() reports the whole directory as wasted.
+func calculateDirAllocatedSize(fsys FS, dir string) int64 {
+	var total int64
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return total
+	}
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name)
+		if e.IsDir {
+			if isSymlinkedDir(fsys, path) {
+				continue
+			}
+			total += calculateDirAllocatedSize(fsys, path)
+			continue
+		}
+		meta, err := fsys.Stat(path)
+		if err != nil {
+			continue
+		}
+		total += meta.AllocatedSize
+	}
+	return total
+}
+
+// largeFileThreshold is the size above which an individual file is reported
+// alongside directory entries instead of only contributing to their totals.
+const largeFileThreshold = 100 * 1024 * 1024
+
+// scanDirectory lists the immediate children of dir via fsys, computing
+// recursive sizes for subdirectories and flagging files over
+// largeFileThreshold.
+func scanDirectory(fsys FS, dir string) (entries []FileInfo, largeFiles []FileInfo, totalSize int64, err error) {
+	items, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	for _, item := range items {
+		if skipPatterns[item.Name] {
+			continue
+		}
+		path := filepath.Join(dir, item.Name)
+
+		if item.IsDir {
+			// A symlinked/junction directory isn't walked: besides the risk
+			// of a junction pointing back at an ancestor recursing forever,
+			// its contents are really reported wherever the link's target
+			// lives, not duplicated here.
+			if isSymlinkedDir(fsys, path) {
+				fi := FileInfo{Name: item.Name, Path: path, IsDir: true, Cleanable: cleanablePatterns[item.Name]}
+				entries = append(entries, fi)
+				continue
+			}
+
+			size := calculateDirSize(fsys, path)
+			fi := FileInfo{
+				Name:      item.Name,
+				Path:      path,
+				Size:      size,
+				IsDir:     true,
+				Cleanable: cleanablePatterns[item.Name],
+			}
+			if meta, err := fsys.Stat(path); err == nil {
+				fi.AllocatedSize, fi.IsSparse = meta.AllocatedSize, meta.IsSparse
+			} else {
+				fi.AllocatedSize = size
+			}
+			entries = append(entries, fi)
+			totalSize += size
+			continue
+		}
+
+		meta, err := fsys.Stat(path)
+		if err != nil {
+			continue
+		}
+		fi := FileInfo{
+			Name:          item.Name,
+			Path:          path,
+			Size:          meta.Size,
+			AllocatedSize: meta.AllocatedSize,
+			IsSparse:      meta.IsSparse,
+		}
+		entries = append(entries, fi)
+		totalSize += meta.Size
+		if meta.Size >= largeFileThreshold {
+			largeFiles = append(largeFiles, fi)
+		}
+	}
+
+	return entries, largeFiles, totalSize, nil
+}
+
+// sortEntries orders entries in place according to mode.
+func sortEntries(entries []FileInfo, mode sortMode) {
+	switch mode {
+	case sortByName:
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	case sortByWasted:
+		sort.Slice(entries, func(i, j int) bool { return entries[i].SparseSavings() > entries[j].SparseSavings() })
+	default:
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Size > entries[j].Size })
+	}
+}
+
+func (m model) Init() tea.Cmd {
+	return nil
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		case "s":
+			m.sortMode = sortBySize
+			sortEntries(m.entries, m.sortMode)
+		case "n":
+			m.sortMode = sortByName
+			sortEntries(m.entries, m.sortMode)
+		case "w":
+			m.sortMode = sortByWasted
+			sortEntries(m.entries, m.sortMode)
+		case " ":
+			if m.cursor < len(m.entries) {
+				p := m.entries[m.cursor].Path
+				m.multiSelected[p] = !m.multiSelected[p]
+			}
+		case "c":
+			if m.cursor < len(m.entries) {
+				path := m.entries[m.cursor].Path
+				if err := compactSparseFile(path); err != nil {
+					m.statusMsg = fmt.Sprintf("compact %s: %v", m.entries[m.cursor].Name, err)
+				} else {
+					m.statusMsg = fmt.Sprintf("compacted %s", m.entries[m.cursor].Name)
+				}
+			}
+		case "d":
+			m.view = viewDedup
+			return m, dedupScanCmd(m.fsys, m.path, m.hashCache)
+		case "a":
+			if m.view == viewDedup {
+				_ = selectAllButNewest(m.fsys, m.dedupIndex, m.multiSelected)
+			}
+		case "esc":
+			m.view = viewEntries
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.entries)-1 {
+				m.cursor++
+			}
+		}
+	case scanResultMsg:
+		m.scanning = false
+		m.entries = msg.entries
+		m.largeFiles = msg.largeFiles
+		m.totalSize = msg.totalSize
+		m.err = msg.err
+		sortEntries(m.entries, m.sortMode)
+	case dedupResultMsg:
+		m.dedupScanned = true
+		m.dedupIndex = msg.index
+		m.err = msg.err
+		if m.hashCache != nil {
+			_ = m.hashCache.Save()
+		}
+	}
+	return m, nil
+}
+
+type scanResultMsg struct {
+	entries    []FileInfo
+	largeFiles []FileInfo
+	totalSize  int64
+	err        error
+}
+
+// dedupResultMsg carries the outcome of a background duplicate scan back to
+// Update, the same way scanResultMsg does for the plain directory scan.
+type dedupResultMsg struct {
+	index DedupIndex
+	err   error
+}
+
+// dedupScanCmd runs buildDedupIndex off the UI goroutine.
+func dedupScanCmd(fsys FS, path string, cache *hashCache) tea.Cmd {
+	return func() tea.Msg {
+		idx, err := buildDedupIndex(fsys, path, cache)
+		return dedupResultMsg{index: idx, err: err}
+	}
+}
+
+func (m model) View() string {
+	if m.scanning {
+		return fmt.Sprintf("scanning %s...\n", m.path)
+	}
+	if m.err != nil {
+		return fmt.Sprintf("error scanning %s: %v\n", m.path, m.err)
+	}
+
+	if m.view == viewDedup {
+		return m.renderDedupView()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s — %s total\n\n", truncatePath(m.path, 60), formatBytes(m.totalSize))
+	for i, e := range m.entries {
+		cursor := "  "
+		if i == m.cursor {
+			cursor = "> "
+		}
+		mark := " "
+		if m.multiSelected[e.Path] {
+			mark = "*"
+		}
+		wasted := ""
+		if saved := e.SparseSavings(); saved > 0 {
+			wasted = fmt.Sprintf(" (wasted %s)", formatBytes(saved))
+		}
+		fmt.Fprintf(&b, "%s[%s] %s %s%s\n", cursor, mark, e.Name, formatBytes(e.Size), wasted)
+	}
+	if m.statusMsg != "" {
+		fmt.Fprintf(&b, "\n%s\n", m.statusMsg)
+	}
+	return b.String()
+}
+
+// renderDedupView shows the duplicate groups found by the last dedup scan,
+// total reclaimable space, and which copies are currently marked for deletion.
+func (m model) renderDedupView() string {
+	if !m.dedupScanned {
+		return fmt.Sprintf("scanning %s for duplicates...\n", m.path)
+	}
+
+	var b strings.Builder
+	reclaimable := dedupReclaimable(m.fsys, m.dedupIndex)
+	fmt.Fprintf(&b, "duplicates under %s — %s reclaimable\n", truncatePath(m.path, 50), formatBytes(reclaimable))
+	fmt.Fprintf(&b, "(a: select all but newest per group, esc: back)\n\n")
+
+	for hash, paths := range m.dedupIndex {
+		fmt.Fprintf(&b, "%s (%d copies)\n", hash[:12], len(paths))
+		for _, p := range paths {
+			mark := " "
+			if m.multiSelected[p] {
+				mark = "*"
+			}
+			fmt.Fprintf(&b, "  [%s] %s\n", mark, p)
+		}
+	}
+	return b.String()
+}
+
+func main() {
+	path := "C:\\"
+	if len(os.Args) > 1 {
+		path = os.Args[1]
+	}
+
+	m := newModel(path)
+	p := tea.NewProgram(m)
+
+	go func() {
+		entries, largeFiles, totalSize, err := scanDirectory(m.fsys, path)
+		p.Send(scanResultMsg{entries: entries, largeFiles: largeFiles, totalSize: totalSize, err: err})
+	}()
+
+	if _, err := p.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "mole analyze: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,95 @@
+//go:build windows
+
+package main
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceExW = modKernel32.NewProc("GetDiskFreeSpaceExW")
+
+// osFS implements FS against the real Windows filesystem, including
+// reparse-point (symlink/junction) detection via Lstat and free-space
+// queries via GetDiskFreeSpaceEx.
+type osFS struct{}
+
+func (osFS) ReadDir(path string) ([]DirEntry, error) {
+	items, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]DirEntry, 0, len(items))
+	for _, item := range items {
+		entries = append(entries, DirEntry{Name: item.Name(), IsDir: item.IsDir()})
+	}
+	return entries, nil
+}
+
+func (osFS) Stat(path string) (FileMeta, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileMeta{}, err
+	}
+	meta := FileMeta{Size: info.Size(), IsDir: info.IsDir(), ModTime: info.ModTime()}
+	if info.IsDir() {
+		// os.Stat follows reparse points, so info.IsDir() is also true for a
+		// junction pointing at a directory. Recursing into one here would
+		// walk the junction's target every time its parent is sized, and a
+		// junction pointing back at an ancestor would never return.
+		if isSymlinkedDir(osFS{}, path) {
+			return meta, nil
+		}
+		meta.AllocatedSize = calculateDirAllocatedSize(osFS{}, path)
+		return meta, nil
+	}
+	allocated, sparse, err := allocatedSize(path, info.Size())
+	if err != nil {
+		meta.AllocatedSize = info.Size()
+		return meta, nil
+	}
+	meta.AllocatedSize = allocated
+	meta.IsSparse = sparse
+	return meta, nil
+}
+
+// Lstat reports the target's own metadata without following it if it's a
+// reparse point (symlink or junction) — callers that want to avoid infinite
+// loops on junction cycles should use this instead of Stat.
+func (osFS) Lstat(path string) (FileMeta, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return FileMeta{}, err
+	}
+	return FileMeta{
+		Size:      info.Size(),
+		IsDir:     info.IsDir(),
+		IsSymlink: info.Mode()&os.ModeSymlink != 0,
+	}, nil
+}
+
+// Open opens path for reading, e.g. to hash it during a dedup scan.
+func (osFS) Open(path string) (io.ReadCloser, error) {
+	return os.Open(path)
+}
+
+// DiskFree reports free and total bytes for the volume containing path.
+func (osFS) DiskFree(path string) (free, total uint64, err error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	var freeBytesAvailable, totalBytes, totalFreeBytes uint64
+	ret, _, callErr := procGetDiskFreeSpaceExW.Call(
+		uintptr(unsafe.Pointer(p)),
+		uintptr(unsafe.Pointer(&freeBytesAvailable)),
+		uintptr(unsafe.Pointer(&totalBytes)),
+		uintptr(unsafe.Pointer(&totalFreeBytes)),
+	)
+	if ret == 0 {
+		return 0, 0, callErr
+	}
+	return freeBytesAvailable, totalBytes, nil
+}
@@ -0,0 +1,273 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	fileAttributeSparseFile = 0x200
+
+	fsctlSetSparse            = 0x000900C4
+	fsctlSetZeroData          = 0x000980C8
+	fsctlQueryAllocatedRanges = 0x000940CF
+)
+
+// zeroScanBlockSize is the granularity compactSparseFile scans allocated
+// ranges at. It matches the smallest NTFS cluster size in common use, since
+// a zero run shorter than one cluster can't be deallocated anyway.
+const zeroScanBlockSize = 4096
+
+var (
+	modKernel32                = syscall.NewLazyDLL("kernel32.dll")
+	procGetCompressedFileSizeW = modKernel32.NewProc("GetCompressedFileSizeW")
+	procGetFileSizeEx          = modKernel32.NewProc("GetFileSizeEx")
+)
+
+// allocatedRange mirrors FILE_ALLOCATED_RANGE_BUFFER, the in/out struct for
+// FSCTL_QUERY_ALLOCATED_RANGES.
+type allocatedRange struct {
+	FileOffset int64
+	Length     int64
+}
+
+// zeroDataRange mirrors FILE_ZERO_DATA_INFORMATION, the input struct for
+// FSCTL_SET_ZERO_DATA. Unlike allocatedRange, its second field is an
+// absolute end offset, not a length.
+type zeroDataRange struct {
+	FileOffset      int64
+	BeyondFinalZero int64
+}
+
+// getCompressedFileSize wraps GetCompressedFileSizeW, which — despite the
+// name — reports actual on-disk usage for sparse files too, not just
+// NTFS-compressed ones.
+func getCompressedFileSize(path string) (int64, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var high uint32
+	low, _, callErr := procGetCompressedFileSizeW.Call(
+		uintptr(unsafe.Pointer(p)),
+		uintptr(unsafe.Pointer(&high)),
+	)
+	if low == 0xFFFFFFFF {
+		if callErr != syscall.Errno(0) {
+			return 0, callErr
+		}
+	}
+	return int64(high)<<32 | int64(uint32(low)), nil
+}
+
+// allocatedSize reports how many bytes path actually occupies on disk
+// (AllocatedSize) and whether it's flagged FILE_ATTRIBUTE_SPARSE_FILE.
+// logicalSize is used as a fallback when the platform call fails so callers
+// always get a sane (if pessimistic) answer.
+func allocatedSize(path string, logicalSize int64) (int64, bool, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return logicalSize, false, err
+	}
+	attrs, err := syscall.GetFileAttributes(p)
+	if err != nil {
+		return logicalSize, false, err
+	}
+	sparse := attrs&fileAttributeSparseFile != 0
+
+	allocated, err := getCompressedFileSize(path)
+	if err != nil {
+		return logicalSize, sparse, err
+	}
+	return allocated, sparse, nil
+}
+
+// getFileSizeEx wraps the Win32 GetFileSizeEx, which isn't exposed by the
+// standard syscall package.
+func getFileSizeEx(handle syscall.Handle) (int64, error) {
+	var size int64
+	ret, _, callErr := procGetFileSizeEx.Call(
+		uintptr(handle),
+		uintptr(unsafe.Pointer(&size)),
+	)
+	if ret == 0 {
+		return 0, callErr
+	}
+	return size, nil
+}
+
+// queryAllocatedRanges returns the byte ranges of path that actually have
+// disk storage behind them, within [0, fileSize). Anything not covered by a
+// returned range is a hole.
+func queryAllocatedRanges(handle syscall.Handle, fileSize int64) ([]allocatedRange, error) {
+	in := allocatedRange{FileOffset: 0, Length: fileSize}
+
+	// FSCTL_QUERY_ALLOCATED_RANGES doesn't tell us how many ranges it'll
+	// return up front; size the output buffer generously and grow once if
+	// the file turns out to be extremely fragmented.
+	out := make([]allocatedRange, 64)
+	for {
+		var bytesReturned uint32
+		err := syscall.DeviceIoControl(
+			handle,
+			fsctlQueryAllocatedRanges,
+			(*byte)(unsafe.Pointer(&in)),
+			uint32(unsafe.Sizeof(in)),
+			(*byte)(unsafe.Pointer(&out[0])),
+			uint32(len(out))*uint32(unsafe.Sizeof(allocatedRange{})),
+			&bytesReturned,
+			nil,
+		)
+		if err == syscall.ERROR_MORE_DATA {
+			out = make([]allocatedRange, len(out)*2)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("FSCTL_QUERY_ALLOCATED_RANGES: %w", err)
+		}
+		n := int(bytesReturned / uint32(unsafe.Sizeof(allocatedRange{})))
+		return out[:n], nil
+	}
+}
+
+// setZeroData tells NTFS to deallocate [zero.FileOffset, zero.BeyondFinalZero)
+// and zero-fill reads over that range, via FSCTL_SET_ZERO_DATA.
+func setZeroData(handle syscall.Handle, zero zeroDataRange) error {
+	var bytesReturned uint32
+	if err := syscall.DeviceIoControl(
+		handle,
+		fsctlSetZeroData,
+		(*byte)(unsafe.Pointer(&zero)),
+		uint32(unsafe.Sizeof(zero)),
+		nil, 0, &bytesReturned, nil,
+	); err != nil {
+		return fmt.Errorf("FSCTL_SET_ZERO_DATA: %w", err)
+	}
+	return nil
+}
+
+// findZeroRuns scans data (which starts at file offset base) for runs of
+// at least one full zeroScanBlockSize-aligned block of zero bytes, and
+// returns each run as an absolute file range. A run shorter than a full
+// block is left alone since NTFS can't deallocate less than a cluster.
+func findZeroRuns(data []byte, base int64) []zeroDataRange {
+	var ranges []zeroDataRange
+	runStart := int64(-1)
+	n := int64(len(data))
+
+	for off := int64(0); off < n; off += zeroScanBlockSize {
+		end := off + zeroScanBlockSize
+		if end > n {
+			end = n
+		}
+		fullBlock := end-off == zeroScanBlockSize
+		isZero := fullBlock && isAllZero(data[off:end])
+
+		if isZero {
+			if runStart == -1 {
+				runStart = off
+			}
+			continue
+		}
+		if runStart != -1 {
+			ranges = append(ranges, zeroDataRange{FileOffset: base + runStart, BeyondFinalZero: base + off})
+			runStart = -1
+		}
+	}
+	if runStart != -1 {
+		ranges = append(ranges, zeroDataRange{FileOffset: base + runStart, BeyondFinalZero: base + n})
+	}
+	return ranges
+}
+
+func isAllZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// compactSparseFile marks path sparse-capable (if it isn't already), then
+// reclaims space two ways: it deallocates the gaps FSCTL_QUERY_ALLOCATED_RANGES
+// already reports as holes, and it reads the content of every allocated
+// range looking for runs of zero bytes NTFS doesn't yet know are holes —
+// the common case for a file that was written densely and never marked
+// sparse — deallocating those too.
+func compactSparseFile(path string) error {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	handle, err := syscall.CreateFile(
+		p,
+		syscall.GENERIC_READ|syscall.GENERIC_WRITE,
+		0,
+		nil,
+		syscall.OPEN_EXISTING,
+		syscall.FILE_ATTRIBUTE_NORMAL,
+		0,
+	)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", path, err)
+	}
+	defer syscall.CloseHandle(handle)
+
+	var bytesReturned uint32
+	if err := syscall.DeviceIoControl(handle, fsctlSetSparse, nil, 0, nil, 0, &bytesReturned, nil); err != nil {
+		return fmt.Errorf("FSCTL_SET_SPARSE: %w", err)
+	}
+
+	fileSize, err := getFileSizeEx(handle)
+	if err != nil {
+		return fmt.Errorf("GetFileSizeEx: %w", err)
+	}
+
+	allocated, err := queryAllocatedRanges(handle, fileSize)
+	if err != nil {
+		return err
+	}
+
+	// Walk the gaps between allocated ranges (and before the first / after
+	// the last) and tell NTFS to deallocate them explicitly.
+	var cursor int64
+	for _, r := range append(allocated, allocatedRange{FileOffset: fileSize}) {
+		if r.FileOffset > cursor {
+			if err := setZeroData(handle, zeroDataRange{FileOffset: cursor, BeyondFinalZero: r.FileOffset}); err != nil {
+				return err
+			}
+		}
+		cursor = r.FileOffset + r.Length
+	}
+
+	// Now scan the content of each allocated range for zero runs NTFS
+	// doesn't already know about, and deallocate those too.
+	buf := make([]byte, 4*1024*1024)
+	for _, r := range allocated {
+		for off := int64(0); off < r.Length; off += int64(len(buf)) {
+			chunkLen := int64(len(buf))
+			if off+chunkLen > r.Length {
+				chunkLen = r.Length - off
+			}
+			absOff := r.FileOffset + off
+			if _, err := syscall.Seek(handle, absOff, 0); err != nil {
+				return fmt.Errorf("seek %d: %w", absOff, err)
+			}
+			n, err := syscall.Read(handle, buf[:chunkLen])
+			if err != nil {
+				return fmt.Errorf("read at %d: %w", absOff, err)
+			}
+			for _, zero := range findZeroRuns(buf[:n], absOff) {
+				if err := setZeroData(handle, zero); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+// makeSparseFile creates an NTFS sparse file of logical size totalSize with
+// only the first writeSize bytes actually written, so its allocated size
+// should come back far smaller than its logical size.
+func makeSparseFile(t *testing.T, path string, totalSize, writeSize int64) {
+	t.Helper()
+
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		t.Fatalf("UTF16PtrFromString: %v", err)
+	}
+	handle, err := syscall.CreateFile(p,
+		syscall.GENERIC_READ|syscall.GENERIC_WRITE,
+		0, nil, syscall.CREATE_ALWAYS, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if err != nil {
+		t.Fatalf("CreateFile: %v", err)
+	}
+	defer syscall.CloseHandle(handle)
+
+	var bytesReturned uint32
+	if err := syscall.DeviceIoControl(handle, fsctlSetSparse, nil, 0, nil, 0, &bytesReturned, nil); err != nil {
+		t.Fatalf("FSCTL_SET_SPARSE: %v", err)
+	}
+
+	if writeSize > 0 {
+		buf := make([]byte, writeSize)
+		for i := range buf {
+			buf[i] = 0xAB
+		}
+		var written uint32
+		if err := syscall.WriteFile(handle, buf, &written, nil); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	if _, err := syscall.SetFilePointer(handle, 0, nil, syscall.FILE_BEGIN); err != nil {
+		t.Fatalf("SetFilePointer: %v", err)
+	}
+	if _, err := setEndOfFileAt(handle, totalSize); err != nil {
+		t.Fatalf("set file size: %v", err)
+	}
+}
+
+// setEndOfFileAt seeks to offset and calls SetEndOfFile, growing the file as
+// a sparse hole beyond whatever was actually written.
+func setEndOfFileAt(handle syscall.Handle, offset int64) (int64, error) {
+	newPos, err := syscall.SetFilePointer(handle, int32(offset), nil, syscall.FILE_BEGIN)
+	if err != nil {
+		return 0, err
+	}
+	r, _, errno := syscall.NewLazyDLL("kernel32.dll").NewProc("SetEndOfFile").Call(uintptr(handle))
+	if r == 0 {
+		return 0, errno
+	}
+	return int64(newPos), nil
+}
+
+func TestAllocatedSizeReportsSparseHoles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sparse.bin")
+
+	const logical = 64 * 1024 * 1024 // 64MB logical
+	const written = 4096             // 4KB actually written
+
+	makeSparseFile(t, path, logical, written)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size() != logical {
+		t.Fatalf("logical size = %d, want %d", info.Size(), logical)
+	}
+
+	allocated, sparse, err := allocatedSize(path, info.Size())
+	if err != nil {
+		t.Fatalf("allocatedSize: %v", err)
+	}
+	if !sparse {
+		t.Error("expected file to be flagged sparse")
+	}
+	if allocated >= logical {
+		t.Errorf("allocated size = %d, expected far less than logical %d", allocated, logical)
+	}
+
+	fi := FileInfo{Size: logical, AllocatedSize: allocated}
+	if fi.SparseSavings() <= 0 {
+		t.Errorf("SparseSavings() = %d, expected > 0", fi.SparseSavings())
+	}
+}
+
+func TestQueryAllocatedRanges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ranges.bin")
+	makeSparseFile(t, path, 16*1024*1024, 8192)
+
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		t.Fatalf("UTF16PtrFromString: %v", err)
+	}
+	handle, err := syscall.CreateFile(p, syscall.GENERIC_READ, syscall.FILE_SHARE_READ, nil, syscall.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		t.Fatalf("CreateFile: %v", err)
+	}
+	defer syscall.CloseHandle(handle)
+
+	ranges, err := queryAllocatedRanges(handle, 16*1024*1024)
+	if err != nil {
+		t.Fatalf("queryAllocatedRanges: %v", err)
+	}
+	if len(ranges) == 0 {
+		t.Fatal("expected at least one allocated range for the written prefix")
+	}
+	if ranges[0].FileOffset != 0 || ranges[0].Length < 8192 {
+		t.Errorf("unexpected first range %+v", ranges[0])
+	}
+
+	_ = unsafe.Sizeof(allocatedRange{}) // sanity: struct must stay two int64s
+}
+
+func TestFindZeroRuns(t *testing.T) {
+	block := make([]byte, zeroScanBlockSize)
+	nonZero := make([]byte, zeroScanBlockSize)
+	for i := range nonZero {
+		nonZero[i] = 0xAB
+	}
+
+	data := append(append(append([]byte{}, nonZero...), block...), nonZero...)
+	ranges := findZeroRuns(data, 1000)
+	if len(ranges) != 1 {
+		t.Fatalf("findZeroRuns() = %d ranges, want 1", len(ranges))
+	}
+	want := zeroDataRange{
+		FileOffset:      1000 + zeroScanBlockSize,
+		BeyondFinalZero: 1000 + 2*zeroScanBlockSize,
+	}
+	if ranges[0] != want {
+		t.Errorf("findZeroRuns() range = %+v, want %+v", ranges[0], want)
+	}
+
+	// A zero run shorter than a full block can't be deallocated, so it's ignored.
+	short := append(append([]byte{}, nonZero...), make([]byte, zeroScanBlockSize/2)...)
+	if ranges := findZeroRuns(short, 0); len(ranges) != 0 {
+		t.Errorf("findZeroRuns() on a sub-block zero tail = %+v, want none", ranges)
+	}
+}
+
+// TestCompactSparseFileReclaimsEmbeddedZeroRuns writes a file densely (never
+// marked sparse) with a multi-block run of zero bytes in the middle, then
+// checks compactSparseFile finds and deallocates that run even though
+// queryAllocatedRanges considered the whole file "allocated" beforehand.
+func TestCompactSparseFileReclaimsEmbeddedZeroRuns(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dense.bin")
+
+	nonZero := make([]byte, zeroScanBlockSize)
+	for i := range nonZero {
+		nonZero[i] = 0xCD
+	}
+	zeroRun := make([]byte, 16*zeroScanBlockSize)
+	content := append(append(append([]byte{}, nonZero...), zeroRun...), nonZero...)
+
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	beforeAllocated, _, err := allocatedSize(path, int64(len(content)))
+	if err != nil {
+		t.Fatalf("allocatedSize before: %v", err)
+	}
+
+	if err := compactSparseFile(path); err != nil {
+		t.Fatalf("compactSparseFile: %v", err)
+	}
+
+	afterAllocated, sparse, err := allocatedSize(path, int64(len(content)))
+	if err != nil {
+		t.Fatalf("allocatedSize after: %v", err)
+	}
+	if !sparse {
+		t.Error("expected file to be flagged sparse after compaction")
+	}
+	if afterAllocated >= beforeAllocated {
+		t.Errorf("allocated size after compaction = %d, want less than before (%d)", afterAllocated, beforeAllocated)
+	}
+}
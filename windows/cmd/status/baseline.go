@@ -0,0 +1,135 @@
+//go:build windows
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ewmaAlpha is the EWMA smoothing factor: alpha≈0.05 gives roughly a
+// 20-sample half-life, so a baseline adapts to a machine's normal load over
+// a few minutes of per-second snapshots without chasing every tick.
+const ewmaAlpha = 0.05
+
+// warmupSamples is how many snapshots a metric needs before its EWMA
+// baseline is trusted; before that, calculateHealthScore falls back to the
+// fixed thresholds it always used.
+const warmupSamples = 20
+
+// varianceEpsilon keeps the z-score finite for a metric that has been
+// perfectly flat so far (variance == 0) instead of dividing by zero the
+// first time it moves at all.
+const varianceEpsilon = 1e-6
+
+// anomalyBaseline tracks an exponentially-weighted mean and variance for one
+// metric series, per Welford/EWMA-style online variance estimation.
+type anomalyBaseline struct {
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+	Samples  int     `json:"samples"`
+}
+
+// baselineStore holds one anomalyBaseline per metric series (e.g.
+// "cpu_percent" or "disk_used_percent{device=C:}") and is safe for
+// concurrent use from the collector's tick loop.
+type baselineStore struct {
+	mu      sync.Mutex
+	metrics map[string]*anomalyBaseline
+}
+
+func newBaselineStore() *baselineStore {
+	return &baselineStore{metrics: make(map[string]*anomalyBaseline)}
+}
+
+// defaultBaselines is the process-wide baseline tracker calculateHealthScore
+// reads and updates. A package-level singleton keeps calculateHealthScore's
+// signature unchanged for callers (and for the existing table-driven tests),
+// while still letting successive snapshots build up real history.
+var defaultBaselines = newBaselineStore()
+
+// update folds x into metric's EWMA baseline and returns the z-score of x
+// against the baseline *before* this update, plus whether the baseline has
+// seen enough samples to be trusted yet.
+func (s *baselineStore) update(metric string, x float64) (z float64, warm bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.metrics[metric]
+	if !ok {
+		b = &anomalyBaseline{Mean: x}
+		s.metrics[metric] = b
+	}
+
+	z = (x - b.Mean) / math.Sqrt(b.Variance+varianceEpsilon)
+
+	prevMean := b.Mean
+	b.Mean = ewmaAlpha*x + (1-ewmaAlpha)*b.Mean
+	b.Variance = (1 - ewmaAlpha) * (b.Variance + ewmaAlpha*(x-prevMean)*(x-prevMean))
+	if b.Samples < warmupSamples {
+		b.Samples++
+	}
+	warm = b.Samples >= warmupSamples
+
+	return z, warm
+}
+
+// sigmoidPenalty maps a z-score to a 0-40 health penalty: comfortably near
+// baseline costs almost nothing, a few sigma out costs the same as the old
+// hard disk-critical threshold did.
+func sigmoidPenalty(z float64) float64 {
+	if z < 0 {
+		return 0 // running cooler than usual is never a health problem
+	}
+	return 40 / (1 + math.Exp(-(z-3)))
+}
+
+// LoadBaselines populates s from a JSON file previously written by Save. A
+// missing or unreadable file just leaves s empty — baselines rebuild from
+// scratch, with the usual warm-up period.
+func (s *baselineStore) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var metrics map[string]*anomalyBaseline
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metrics = metrics
+	return nil
+}
+
+// Save persists s to path as JSON, creating parent directories as needed.
+func (s *baselineStore) Save(path string) error {
+	s.mu.Lock()
+	data, err := json.Marshal(s.metrics)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// defaultBaselinesPath is where health-score baselines are persisted
+// between runs.
+func defaultBaselinesPath() string {
+	dir := os.Getenv("LOCALAPPDATA")
+	if dir == "" {
+		dir = "."
+	}
+	return filepath.Join(dir, "Mole", "baselines.json")
+}
+
+func diskSeriesKey(device string) string {
+	return fmt.Sprintf("disk_used_percent{device=%s}", device)
+}
@@ -0,0 +1,81 @@
+//go:build windows
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBaselineStoreWarmup(t *testing.T) {
+	s := newBaselineStore()
+	for i := 0; i < warmupSamples-1; i++ {
+		_, warm := s.update("metric", 50)
+		if warm {
+			t.Fatalf("sample %d: expected not warm before %d samples", i, warmupSamples)
+		}
+	}
+	_, warm := s.update("metric", 50)
+	if !warm {
+		t.Errorf("expected warm after %d samples", warmupSamples)
+	}
+}
+
+func TestBaselineStoreSteadyStateDoesNotFlagConstantLoad(t *testing.T) {
+	s := newBaselineStore()
+	var lastZ float64
+	var lastWarm bool
+	for i := 0; i < warmupSamples+50; i++ {
+		lastZ, lastWarm = s.update("cpu_percent", 90) // machine that always idles hot
+	}
+	if !lastWarm {
+		t.Fatal("expected baseline to be warm after many samples")
+	}
+	if math.Abs(lastZ) > 1 {
+		t.Errorf("z-score for a constant 90%% load = %v, want close to 0", lastZ)
+	}
+	if penalty := sigmoidPenalty(lastZ); penalty > 5 {
+		t.Errorf("sigmoidPenalty(%v) = %v, expected a machine always at its own baseline to score healthy", lastZ, penalty)
+	}
+}
+
+func TestBaselineStoreSpikeScoresBadly(t *testing.T) {
+	s := newBaselineStore()
+	for i := 0; i < warmupSamples+50; i++ {
+		s.update("cpu_percent", 20) // normally-idle machine
+	}
+	z, warm := s.update("cpu_percent", 95) // sudden spike
+	if !warm {
+		t.Fatal("expected baseline to be warm")
+	}
+	if z <= 2 {
+		t.Errorf("z-score for a spike from a 20%% baseline to 95%% = %v, want > 2", z)
+	}
+	if penalty := sigmoidPenalty(z); penalty < 20 {
+		t.Errorf("sigmoidPenalty(%v) = %v, expected a sharp spike to score badly", z, penalty)
+	}
+}
+
+func TestCalculateHealthScoreNamesWorstAnomalousMetricOnceWarm(t *testing.T) {
+	defaultBaselines = newBaselineStore() // isolate from other tests' global state
+	for i := 0; i < warmupSamples+5; i++ {
+		calculateHealthScore(MetricsSnapshot{CPUPercent: 20, MemPercent: 30, SwapPercent: 5})
+	}
+
+	score, msg := calculateHealthScore(MetricsSnapshot{CPUPercent: 97, MemPercent: 30, SwapPercent: 5})
+	if score >= 90 {
+		t.Errorf("score = %d, expected a CPU spike against a 20%% baseline to score poorly", score)
+	}
+	if !containsCPU(msg) {
+		t.Errorf("message %q does not name CPU as the worst anomalous metric", msg)
+	}
+}
+
+func containsCPU(s string) bool {
+	for i := 0; i+3 <= len(s); i++ {
+		if s[i:i+3] == "CPU" {
+			return true
+		}
+	}
+	return false
+}
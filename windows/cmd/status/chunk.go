@@ -0,0 +1,365 @@
+//go:build windows
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const chunkMagic = "MOLC"
+
+// writeChunk encodes samps (already sorted by time) using delta-of-delta
+// timestamps and XOR'd floats — the same scheme Facebook's Gorilla TSDB
+// uses, which is what lets a day of per-second samples fit in a few KB.
+func writeChunk(dir, seriesKey string, samps []walSample) (chunkMeta, error) {
+	name := fmt.Sprintf("%s_%d.chunk", sanitizeSeriesKey(seriesKey), samps[0].ts.UnixNano())
+	path := filepath.Join(dir, name)
+
+	bw := newBitWriter()
+	encodeSamples(bw, samps)
+	body := bw.bytes()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return chunkMeta{}, err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	if _, err := w.WriteString(chunkMagic); err != nil {
+		return chunkMeta{}, err
+	}
+	if err := writeUvarintTo(w, uint64(len(seriesKey))); err != nil {
+		return chunkMeta{}, err
+	}
+	if _, err := w.WriteString(seriesKey); err != nil {
+		return chunkMeta{}, err
+	}
+	if err := writeUvarintTo(w, uint64(len(samps))); err != nil {
+		return chunkMeta{}, err
+	}
+	if err := writeVarintTo(w, samps[0].ts.UnixNano()); err != nil {
+		return chunkMeta{}, err
+	}
+	if err := writeVarintTo(w, samps[len(samps)-1].ts.UnixNano()); err != nil {
+		return chunkMeta{}, err
+	}
+	if err := writeUvarintTo(w, uint64(len(body))); err != nil {
+		return chunkMeta{}, err
+	}
+	if _, err := w.Write(body); err != nil {
+		return chunkMeta{}, err
+	}
+	if err := w.Flush(); err != nil {
+		return chunkMeta{}, err
+	}
+
+	return chunkMeta{
+		path:     path,
+		minTime:  samps[0].ts,
+		maxTime:  samps[len(samps)-1].ts,
+		numSamps: len(samps),
+	}, nil
+}
+
+// readChunkMeta reads just the header of a chunk file, without decoding its
+// samples — used to rebuild the in-memory chunk index on startup.
+func readChunkMeta(path string) (chunkMeta, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return chunkMeta{}, "", err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	magic := make([]byte, len(chunkMagic))
+	if _, err := r.Read(magic); err != nil || string(magic) != chunkMagic {
+		return chunkMeta{}, "", fmt.Errorf("not a mole chunk file")
+	}
+	keyLen, err := binary.ReadUvarint(r)
+	if err != nil {
+		return chunkMeta{}, "", err
+	}
+	keyBuf := make([]byte, keyLen)
+	if _, err := r.Read(keyBuf); err != nil {
+		return chunkMeta{}, "", err
+	}
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return chunkMeta{}, "", err
+	}
+	minNanos, err := binary.ReadVarint(r)
+	if err != nil {
+		return chunkMeta{}, "", err
+	}
+	maxNanos, err := binary.ReadVarint(r)
+	if err != nil {
+		return chunkMeta{}, "", err
+	}
+
+	return chunkMeta{
+		path:     path,
+		minTime:  time.Unix(0, minNanos),
+		maxTime:  time.Unix(0, maxNanos),
+		numSamps: int(count),
+	}, string(keyBuf), nil
+}
+
+// readChunk fully decodes a chunk file back into Samples.
+func readChunk(path string) ([]Sample, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) < len(chunkMagic) || string(data[:len(chunkMagic)]) != chunkMagic {
+		return nil, "", fmt.Errorf("not a mole chunk file")
+	}
+	data = data[len(chunkMagic):]
+
+	keyLen, n := binary.Uvarint(data)
+	data = data[n:]
+	seriesKey := string(data[:keyLen])
+	data = data[keyLen:]
+
+	count, n := binary.Uvarint(data)
+	data = data[n:]
+
+	_, n = binary.Varint(data) // minTime, unused once we have the body
+	data = data[n:]
+	_, n = binary.Varint(data) // maxTime
+	data = data[n:]
+
+	bodyLen, n := binary.Uvarint(data)
+	data = data[n:]
+	body := data[:bodyLen]
+
+	samples := decodeSamples(newBitReader(body), int(count))
+	return samples, seriesKey, nil
+}
+
+// encodeSamples writes the first sample raw, the second as a plain delta,
+// and every sample after that as a delta-of-delta plus a Gorilla-XOR'd
+// value, per the Facebook Gorilla paper's point encoding.
+func encodeSamples(bw *bitWriter, samps []walSample) {
+	prevTS := samps[0].ts.UnixNano()
+	bw.writeBits(uint64(prevTS), 64)
+	bw.writeBits(math.Float64bits(samps[0].value), 64)
+	prevValue := samps[0].value
+	if len(samps) == 1 {
+		return
+	}
+
+	delta := samps[1].ts.UnixNano() - prevTS
+	bw.writeBits(zigzag(delta), 64)
+	writeXOR(bw, math.Float64bits(prevValue), math.Float64bits(samps[1].value))
+	prevTS = samps[1].ts.UnixNano()
+	prevValue = samps[1].value
+
+	for i := 2; i < len(samps); i++ {
+		ts := samps[i].ts.UnixNano()
+		newDelta := ts - prevTS
+		writeDod(bw, newDelta-delta)
+		delta = newDelta
+		writeXOR(bw, math.Float64bits(prevValue), math.Float64bits(samps[i].value))
+		prevTS = ts
+		prevValue = samps[i].value
+	}
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func decodeSamples(br *bitReader, count int) []Sample {
+	if count == 0 {
+		return nil
+	}
+	out := make([]Sample, 0, count)
+
+	ts := int64(br.readBits(64))
+	value := math.Float64frombits(br.readBits(64))
+	out = append(out, Sample{Timestamp: time.Unix(0, ts), Value: value})
+	if count == 1 {
+		return out
+	}
+
+	delta := unzigzag(br.readBits(64))
+	ts += delta
+	value = math.Float64frombits(decodeXOR(br, math.Float64bits(value)))
+	out = append(out, Sample{Timestamp: time.Unix(0, ts), Value: value})
+
+	for i := 2; i < count; i++ {
+		dod := readDod(br)
+		delta += dod
+		ts += delta
+		value = math.Float64frombits(decodeXOR(br, math.Float64bits(value)))
+		out = append(out, Sample{Timestamp: time.Unix(0, ts), Value: value})
+	}
+	return out
+}
+
+// readDod/writeDod implement Gorilla's variable-width delta-of-delta coding:
+// a run of 1-bits selects the width of the following signed value, with 0
+// itself coded as a single 0 bit (the overwhelmingly common case for
+// regularly-spaced samples).
+func writeDod(bw *bitWriter, dod int64) {
+	switch {
+	case dod == 0:
+		bw.writeBits(0, 1)
+	case -64 <= dod && dod <= 63:
+		bw.writeBits(0b10, 2)
+		bw.writeBits(uint64(dod)&0x7f, 7)
+	case -256 <= dod && dod <= 255:
+		bw.writeBits(0b110, 3)
+		bw.writeBits(uint64(dod)&0x1ff, 9)
+	case -2048 <= dod && dod <= 2047:
+		bw.writeBits(0b1110, 4)
+		bw.writeBits(uint64(dod)&0xfff, 12)
+	default:
+		bw.writeBits(0b1111, 4)
+		bw.writeBits(uint64(dod), 64)
+	}
+}
+
+func readDod(br *bitReader) int64 {
+	if br.readBits(1) == 0 {
+		return 0
+	}
+	if br.readBits(1) == 0 {
+		return signExtend(br.readBits(7), 7)
+	}
+	if br.readBits(1) == 0 {
+		return signExtend(br.readBits(9), 9)
+	}
+	if br.readBits(1) == 0 {
+		return signExtend(br.readBits(12), 12)
+	}
+	return int64(br.readBits(64))
+}
+
+func signExtend(v uint64, nbits int) int64 {
+	shift := 64 - uint(nbits)
+	return int64(v<<shift) >> shift
+}
+
+// writeXOR/decodeXOR implement Gorilla's XOR float encoding: a 0 bit means
+// "identical to the previous value", otherwise the leading/trailing zero
+// counts of the XOR and the meaningful bits in between are written.
+func writeXOR(bw *bitWriter, prevBits, curBits uint64) {
+	xor := prevBits ^ curBits
+	if xor == 0 {
+		bw.writeBits(0, 1)
+		return
+	}
+	bw.writeBits(1, 1)
+	leading := bits.LeadingZeros64(xor)
+	trailing := bits.TrailingZeros64(xor)
+	meaningful := 64 - leading - trailing
+	bw.writeBits(uint64(leading), 5)
+	bw.writeBits(uint64(meaningful), 6)
+	bw.writeBits((xor>>uint(trailing))&((1<<uint(meaningful))-1), meaningful)
+}
+
+func decodeXOR(br *bitReader, prevBits uint64) uint64 {
+	if br.readBits(1) == 0 {
+		return prevBits
+	}
+	leading := int(br.readBits(5))
+	meaningful := int(br.readBits(6))
+	trailing := 64 - leading - meaningful
+	meaningfulBits := br.readBits(meaningful)
+	xor := meaningfulBits << uint(trailing)
+	return prevBits ^ xor
+}
+
+func sanitizeSeriesKey(key string) string {
+	r := strings.NewReplacer("{", "_", "}", "_", "=", "-", ",", "_", ":", "-", "\\", "_", "/", "_")
+	return r.Replace(key)
+}
+
+func writeUvarintTo(w *bufio.Writer, v uint64) error {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	_, err := w.Write(buf[:n])
+	return err
+}
+
+func writeVarintTo(w *bufio.Writer, v int64) error {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buf[:], v)
+	_, err := w.Write(buf[:n])
+	return err
+}
+
+// bitWriter/bitReader are tiny MSB-first bit-packing helpers used by the
+// Gorilla-style encoder above.
+type bitWriter struct {
+	buf  []byte
+	cur  byte
+	bits uint8 // bits used in cur
+}
+
+func newBitWriter() *bitWriter { return &bitWriter{} }
+
+func (bw *bitWriter) writeBits(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		bit := byte((v >> uint(i)) & 1)
+		bw.cur = bw.cur<<1 | bit
+		bw.bits++
+		if bw.bits == 8 {
+			bw.buf = append(bw.buf, bw.cur)
+			bw.cur = 0
+			bw.bits = 0
+		}
+	}
+}
+
+func (bw *bitWriter) bytes() []byte {
+	if bw.bits > 0 {
+		bw.buf = append(bw.buf, bw.cur<<uint(8-bw.bits))
+		bw.cur = 0
+		bw.bits = 0
+	}
+	return bw.buf
+}
+
+type bitReader struct {
+	buf  []byte
+	pos  int // next byte
+	bits uint8
+	cur  byte
+}
+
+func newBitReader(buf []byte) *bitReader { return &bitReader{buf: buf} }
+
+func (br *bitReader) readBits(n int) uint64 {
+	var v uint64
+	for i := 0; i < n; i++ {
+		if br.bits == 0 {
+			if br.pos < len(br.buf) {
+				br.cur = br.buf[br.pos]
+				br.pos++
+			} else {
+				br.cur = 0
+			}
+			br.bits = 8
+		}
+		bit := (br.cur >> 7) & 1
+		br.cur <<= 1
+		br.bits--
+		v = v<<1 | uint64(bit)
+	}
+	return v
+}
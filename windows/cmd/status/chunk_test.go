@@ -0,0 +1,22 @@
+//go:build windows
+
+package main
+
+import "testing"
+
+// TestDodBoundaryValuesRoundTrip guards the variable-width dod encoding's
+// bit-width boundaries: a 7/9/12-bit two's-complement field can only hold
+// [-64,63]/[-256,255]/[-2048,2047], so a delta-of-delta landing exactly on
+// the old (off-by-one) inclusive upper bounds of 64/256/2048 must fall
+// through to the next wider width instead of wrapping to a negative value.
+func TestDodBoundaryValuesRoundTrip(t *testing.T) {
+	for _, dod := range []int64{0, 1, -1, 63, 64, -64, -65, 255, 256, -256, -257, 2047, 2048, -2048, -2049} {
+		bw := newBitWriter()
+		writeDod(bw, dod)
+		br := newBitReader(bw.bytes())
+		got := readDod(br)
+		if got != dod {
+			t.Errorf("writeDod/readDod(%d) round-tripped as %d", dod, got)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+//go:build windows
+
+// Command status renders a live terminal dashboard of system health:
+// CPU, memory, swap, disk and network utilization, refreshed on a tick.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/mem"
+	gnet "github.com/shirou/gopsutil/v3/net"
+)
+
+// DiskInfo is a point-in-time reading for a single mounted volume.
+type DiskInfo struct {
+	Device      string
+	UsedPercent float64
+	Total       uint64
+	Used        uint64
+}
+
+// MetricsSnapshot is one sample of the machine's vitals.
+type MetricsSnapshot struct {
+	Timestamp   time.Time
+	CPUPercent  float64
+	MemPercent  float64
+	SwapPercent float64
+	Disks       []DiskInfo
+	NetSent     uint64
+	NetRecv     uint64
+}
+
+// netCounters is the subset of gopsutil's IOCountersStat we diff between ticks.
+type netCounters struct {
+	BytesSent uint64
+	BytesRecv uint64
+}
+
+// Collector gathers MetricsSnapshots and optionally persists them.
+type Collector struct {
+	prevNet map[string]netCounters
+	store   *tsdbStore
+	opts    CollectorOptions
+}
+
+// NewCollector returns a Collector that only keeps metrics in memory.
+func NewCollector() *Collector {
+	return &Collector{
+		prevNet: make(map[string]netCounters),
+	}
+}
+
+// NewCollectorWithOptions returns a Collector that also appends every
+// snapshot it gathers to an on-disk time-series store. See CollectorOptions.
+func NewCollectorWithOptions(opts CollectorOptions) (*Collector, error) {
+	c := NewCollector()
+	store, err := newTSDBStore(opts)
+	if err != nil {
+		return nil, fmt.Errorf("open tsdb store: %w", err)
+	}
+	c.store = store
+	c.opts = opts
+	return c, nil
+}
+
+// Close releases resources held by the collector, flushing the WAL if one is open.
+func (c *Collector) Close() error {
+	if c.store == nil {
+		return nil
+	}
+	return c.store.Close()
+}
+
+// Collect samples the current system state. If the collector was built with
+// NewCollectorWithOptions, the snapshot is also appended to the WAL.
+func (c *Collector) Collect() (MetricsSnapshot, error) {
+	snap := MetricsSnapshot{Timestamp: time.Now()}
+
+	cpuPercents, err := cpu.Percent(0, false)
+	if err != nil {
+		return snap, fmt.Errorf("read cpu percent: %w", err)
+	}
+	if len(cpuPercents) > 0 {
+		snap.CPUPercent = cpuPercents[0]
+	}
+
+	vm, err := mem.VirtualMemory()
+	if err != nil {
+		return snap, fmt.Errorf("read memory: %w", err)
+	}
+	snap.MemPercent = vm.UsedPercent
+
+	sw, err := mem.SwapMemory()
+	if err != nil {
+		return snap, fmt.Errorf("read swap: %w", err)
+	}
+	snap.SwapPercent = sw.UsedPercent
+
+	partitions, err := disk.Partitions(false)
+	if err != nil {
+		return snap, fmt.Errorf("list partitions: %w", err)
+	}
+	for _, p := range partitions {
+		usage, err := disk.Usage(p.Mountpoint)
+		if err != nil {
+			continue
+		}
+		snap.Disks = append(snap.Disks, DiskInfo{
+			Device:      p.Device,
+			UsedPercent: usage.UsedPercent,
+			Total:       usage.Total,
+			Used:        usage.Used,
+		})
+	}
+
+	if counters, err := gnet.IOCounters(false); err == nil && len(counters) > 0 {
+		cur := netCounters{BytesSent: counters[0].BytesSent, BytesRecv: counters[0].BytesRecv}
+		if prev, ok := c.prevNet["total"]; ok {
+			snap.NetSent = cur.BytesSent - prev.BytesSent
+			snap.NetRecv = cur.BytesRecv - prev.BytesRecv
+		}
+		c.prevNet["total"] = cur
+	}
+
+	if c.store != nil {
+		c.store.appendSnapshot(snap)
+	}
+
+	return snap, nil
+}
+
+// Query returns the samples recorded for metric within [from, to]. It only
+// works on a Collector created via NewCollectorWithOptions.
+func (c *Collector) Query(metric string, from, to time.Time) ([]Sample, error) {
+	if c.store == nil {
+		return nil, fmt.Errorf("collector has no tsdb store configured")
+	}
+	return c.store.Query(metric, from, to)
+}
+
+// calculateHealthScore turns a snapshot into a 0-100 score and a short
+// human-readable explanation of the worst offender.
+//
+// Each metric has a per-machine EWMA baseline (mean + variance) in
+// defaultBaselines, so a box that always idles at 90% CPU isn't perpetually
+// flagged unhealthy, but a sudden spike away from its own normal still
+// scores badly. Until a metric has warmupSamples snapshots behind it, its
+// baseline isn't trusted yet and it falls back to the original fixed
+// thresholds instead.
+func calculateHealthScore(s MetricsSnapshot) (int, string) {
+	score := 100
+	worst := ""
+	worstPenalty := 0.0
+
+	evaluate := func(label, seriesKey string, percent, softLimit, hardLimit float64) {
+		z, warm := defaultBaselines.update(seriesKey, percent)
+
+		var penalty float64
+		if warm {
+			penalty = sigmoidPenalty(z)
+		} else {
+			switch {
+			case percent >= hardLimit:
+				penalty = 40
+			case percent >= softLimit:
+				penalty = 20 * (percent - softLimit) / (hardLimit - softLimit)
+			}
+		}
+
+		if penalty > worstPenalty {
+			worstPenalty = penalty
+			if warm {
+				worst = fmt.Sprintf("%s %.1fσ above baseline", label, z)
+			} else {
+				worst = fmt.Sprintf("%s at %.0f%%", label, percent)
+			}
+		}
+		score -= int(penalty)
+	}
+
+	evaluate("CPU", "cpu_percent", s.CPUPercent, 80, 95)
+	evaluate("Memory", "mem_percent", s.MemPercent, 80, 95)
+	evaluate("Swap", "swap_percent", s.SwapPercent, 50, 90)
+	for _, d := range s.Disks {
+		evaluate(fmt.Sprintf("Disk %s", d.Device), diskSeriesKey(d.Device), d.UsedPercent, 85, 95)
+	}
+
+	if score < 0 {
+		score = 0
+	}
+	if score > 100 {
+		score = 100
+	}
+	if worst == "" {
+		worst = "all metrics nominal"
+	}
+	return score, worst
+}
+
+// formatBytes renders a byte count using the largest whole unit that keeps
+// at least one significant digit before the decimal point.
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	units := []string{"KB", "MB", "GB", "TB", "PB"}
+	return fmt.Sprintf("%.1f %s", float64(b)/float64(div), units[exp])
+}
+
+// formatDuration renders a duration as "Xd Yh Zm", dropping leading zero units.
+func formatDuration(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	}
+	if hours > 0 {
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+// truncateString shortens s to maxLen runes, appending "..." when it doesn't fit.
+func truncateString(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	if maxLen <= 3 {
+		return s[:maxLen]
+	}
+	return s[:maxLen-3] + "..."
+}
+
+var moleFrames = []string{"🐹", "🐭", "🐹", "🐁"}
+
+// getMoleFrame returns the spinner glyph for frame index i, or "" when hidden
+// is true so callers can blink it off every other tick.
+func getMoleFrame(i int, hidden bool) string {
+	if hidden {
+		return ""
+	}
+	return moleFrames[i%len(moleFrames)]
+}
+
+// renderProgressBar draws a width-wide bar filled to percent.
+func renderProgressBar(percent float64, width int) string {
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	filled := int(percent / 100 * float64(width))
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", width-filled) + "]"
+}
+
+// getPercentColor maps a utilization percentage to a traffic-light color.
+func getPercentColor(percent float64) lipgloss.Color {
+	switch {
+	case percent >= 90:
+		return lipgloss.Color("196") // red
+	case percent >= 75:
+		return lipgloss.Color("214") // orange
+	default:
+		return lipgloss.Color("46") // green
+	}
+}
+
+// model is the bubbletea model backing the status dashboard.
+type model struct {
+	collector *Collector
+	snapshot  MetricsSnapshot
+	ready     bool
+	frame     int
+	err       error
+}
+
+// newModel returns a fresh dashboard model backed by an in-memory-only
+// collector, not yet collected from. Production use goes through main(),
+// which builds a persistence-backed collector via newModelWithCollector.
+func newModel() model {
+	return newModelWithCollector(NewCollector())
+}
+
+// newModelWithCollector returns a fresh dashboard model backed by collector,
+// not yet collected from.
+func newModelWithCollector(collector *Collector) model {
+	return model{collector: collector}
+}
+
+type tickMsg time.Time
+
+func tickCmd() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+func (m model) Init() tea.Cmd {
+	return tickCmd()
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		}
+	case tickMsg:
+		snap, err := m.collector.Collect()
+		m.snapshot = snap
+		m.err = err
+		m.ready = true
+		m.frame++
+		return m, tickCmd()
+	}
+	return m, nil
+}
+
+func (m model) View() string {
+	if !m.ready {
+		return "gathering metrics...\n"
+	}
+	if m.err != nil {
+		return fmt.Sprintf("error collecting metrics: %v\n", m.err)
+	}
+
+	score, worst := calculateHealthScore(m.snapshot)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s Mole status — health %d/100 (%s)\n\n", getMoleFrame(m.frame, false), score, worst)
+	fmt.Fprintf(&b, "CPU    %s %.0f%%\n", renderProgressBar(m.snapshot.CPUPercent, 30), m.snapshot.CPUPercent)
+	fmt.Fprintf(&b, "Memory %s %.0f%%\n", renderProgressBar(m.snapshot.MemPercent, 30), m.snapshot.MemPercent)
+	fmt.Fprintf(&b, "Swap   %s %.0f%%\n", renderProgressBar(m.snapshot.SwapPercent, 30), m.snapshot.SwapPercent)
+	for _, d := range m.snapshot.Disks {
+		fmt.Fprintf(&b, "%s %s %.0f%%\n", truncateString(d.Device, 6), renderProgressBar(d.UsedPercent, 30), d.UsedPercent)
+	}
+	fmt.Fprintf(&b, "\nnet ↑%s ↓%s\n", formatBytes(m.snapshot.NetSent), formatBytes(m.snapshot.NetRecv))
+	return b.String()
+}
+
+// storeMaintenanceInterval is how often the running program folds its WAL
+// into compacted chunks and prunes anything past the configured retention.
+const storeMaintenanceInterval = 5 * time.Minute
+
+// runStoreMaintenance periodically compacts c's tsdb store and applies
+// retention until stop is closed. A failed pass is logged rather than
+// fatal — the collector keeps appending to the WAL either way, and the
+// next tick just tries again.
+func runStoreMaintenance(c *Collector, stop <-chan struct{}) {
+	ticker := time.NewTicker(storeMaintenanceInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := c.store.Compact(); err != nil {
+				fmt.Fprintf(os.Stderr, "mole status: tsdb compact: %v\n", err)
+				continue
+			}
+			if err := c.store.ApplyRetention(c.opts.Retention); err != nil {
+				fmt.Fprintf(os.Stderr, "mole status: tsdb retention: %v\n", err)
+			}
+		}
+	}
+}
+
+func main() {
+	_ = defaultBaselines.Load(defaultBaselinesPath()) // missing file just means a cold start
+
+	collector, err := NewCollectorWithOptions(DefaultCollectorOptions())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mole status: persisting metrics disabled: %v\n", err)
+		collector = NewCollector()
+	}
+
+	var stopMaintenance, maintenanceDone chan struct{}
+	if collector.store != nil {
+		stopMaintenance = make(chan struct{})
+		maintenanceDone = make(chan struct{})
+		go func() {
+			runStoreMaintenance(collector, stopMaintenance)
+			close(maintenanceDone)
+		}()
+	}
+
+	p := tea.NewProgram(newModelWithCollector(collector))
+	_, err = p.Run()
+
+	// Stop and wait for the maintenance goroutine before closing the store,
+	// so an in-flight Compact() always finishes instead of being cut off by
+	// os.Exit below.
+	if stopMaintenance != nil {
+		close(stopMaintenance)
+		<-maintenanceDone
+	}
+	if closeErr := collector.Close(); closeErr != nil {
+		fmt.Fprintf(os.Stderr, "mole status: closing tsdb store: %v\n", closeErr)
+	}
+
+	if saveErr := defaultBaselines.Save(defaultBaselinesPath()); saveErr != nil {
+		fmt.Fprintf(os.Stderr, "mole status: saving baselines: %v\n", saveErr)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mole status: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -68,6 +68,11 @@ func TestTruncateString(t *testing.T) {
 }
 
 func TestCalculateHealthScore(t *testing.T) {
+	// Run every case cold, before any metric's EWMA baseline warms up, so
+	// this exercises the same fixed thresholds calculateHealthScore always
+	// used pre-warmup. See baseline_test.go for the adaptive, warmed-up path.
+	defaultBaselines = newBaselineStore()
+
 	tests := []struct {
 		name     string
 		snapshot MetricsSnapshot
@@ -164,6 +169,18 @@ func TestNewCollector(t *testing.T) {
 	}
 }
 
+func TestNewModelWithCollectorUsesGivenCollector(t *testing.T) {
+	collector := NewCollector()
+	model := newModelWithCollector(collector)
+
+	if model.collector != collector {
+		t.Error("newModelWithCollector should use the collector it was given")
+	}
+	if model.ready {
+		t.Error("ready should be false initially")
+	}
+}
+
 func TestGetMoleFrame(t *testing.T) {
 	// Test visible frames
 	for i := 0; i < 8; i++ {
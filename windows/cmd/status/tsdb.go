@@ -0,0 +1,507 @@
+//go:build windows
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+// CollectorOptions configures the optional on-disk time-series store that a
+// Collector can append every MetricsSnapshot to.
+type CollectorOptions struct {
+	// StoreDir is the directory the WAL and chunk files live under, e.g.
+	// "%LocalAppData%\Mole\tsdb".
+	StoreDir string
+	// WALSegmentSizeMB is the size a WAL segment grows to before rotating.
+	WALSegmentSizeMB int
+	// Retention is how long compacted chunks are kept before being pruned.
+	Retention time.Duration
+	// CompressWAL snappy-compresses each WAL record before it's written.
+	// Off by default: it costs a little CPU for noticeably smaller segments,
+	// worth it on disk-constrained Windows workstations.
+	CompressWAL bool
+}
+
+// DefaultCollectorOptions returns sane defaults for persisting metrics under
+// the user's local app-data directory.
+func DefaultCollectorOptions() CollectorOptions {
+	dir := os.Getenv("LOCALAPPDATA")
+	if dir == "" {
+		dir = "."
+	}
+	return CollectorOptions{
+		StoreDir:         filepath.Join(dir, "Mole", "tsdb"),
+		WALSegmentSizeMB: 16,
+		Retention:        14 * 24 * time.Hour,
+		CompressWAL:      false,
+	}
+}
+
+// Sample is a single (timestamp, value) point returned from a Query.
+type Sample struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+// tsdbStore is a minimal Prometheus-style time-series store: samples land in
+// a segmented write-ahead log as they arrive, and Compact periodically
+// rewrites the WAL into immutable, more compactly-encoded chunk files.
+type tsdbStore struct {
+	mu   sync.Mutex
+	opts CollectorOptions
+
+	walDir    string
+	chunksDir string
+
+	walSegment *os.File
+	walWriter  *bufio.Writer
+	walPath    string
+	walSize    int64
+	walSeq     int
+
+	chunks map[string][]chunkMeta // seriesKey -> chunk files, oldest first
+}
+
+// chunkMeta describes one immutable chunk file on disk.
+type chunkMeta struct {
+	path     string
+	minTime  time.Time
+	maxTime  time.Time
+	numSamps int
+}
+
+func newTSDBStore(opts CollectorOptions) (*tsdbStore, error) {
+	if opts.StoreDir == "" {
+		opts = DefaultCollectorOptions()
+	}
+	if opts.WALSegmentSizeMB <= 0 {
+		opts.WALSegmentSizeMB = 16
+	}
+
+	s := &tsdbStore{
+		opts:      opts,
+		walDir:    filepath.Join(opts.StoreDir, "wal"),
+		chunksDir: filepath.Join(opts.StoreDir, "chunks"),
+		chunks:    make(map[string][]chunkMeta),
+	}
+	if err := os.MkdirAll(s.walDir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(s.chunksDir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := s.loadChunkIndex(); err != nil {
+		return nil, err
+	}
+	if err := s.openNextSegment(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *tsdbStore) loadChunkIndex() error {
+	entries, err := os.ReadDir(s.chunksDir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".chunk") {
+			continue
+		}
+		path := filepath.Join(s.chunksDir, e.Name())
+		meta, seriesKey, err := readChunkMeta(path)
+		if err != nil {
+			continue // skip corrupt/partial chunk rather than failing startup
+		}
+		s.chunks[seriesKey] = append(s.chunks[seriesKey], meta)
+	}
+	for key := range s.chunks {
+		sort.Slice(s.chunks[key], func(i, j int) bool {
+			return s.chunks[key][i].minTime.Before(s.chunks[key][j].minTime)
+		})
+	}
+	return nil
+}
+
+// openNextSegment starts a fresh WAL segment, picking up from the highest
+// existing sequence number so restarts after a crash don't clobber history.
+func (s *tsdbStore) openNextSegment() error {
+	entries, err := os.ReadDir(s.walDir)
+	if err != nil {
+		return err
+	}
+	maxSeq := 0
+	for _, e := range entries {
+		var seq int
+		if _, err := fmt.Sscanf(e.Name(), "%d.wal", &seq); err == nil && seq > maxSeq {
+			maxSeq = seq
+		}
+	}
+	s.walSeq = maxSeq + 1
+	s.walPath = filepath.Join(s.walDir, fmt.Sprintf("%06d.wal", s.walSeq))
+
+	f, err := os.OpenFile(s.walPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	s.walSegment = f
+	s.walWriter = bufio.NewWriter(f)
+	s.walSize = 0
+	return nil
+}
+
+// Close flushes and closes the active WAL segment.
+func (s *tsdbStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.walWriter != nil {
+		if err := s.walWriter.Flush(); err != nil {
+			return err
+		}
+	}
+	if s.walSegment != nil {
+		return s.walSegment.Close()
+	}
+	return nil
+}
+
+func seriesKeyFor(metric string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return metric
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%s", k, labels[k]))
+	}
+	return fmt.Sprintf("%s{%s}", metric, strings.Join(parts, ","))
+}
+
+// appendSnapshot fans a MetricsSnapshot out into one WAL record per series.
+// A write failure (disk full, permission error, ...) is logged rather than
+// returned, since the WAL is meant to be a best-effort background sink that
+// never blocks the collector's tick loop — but it must not vanish silently,
+// or the durability the WAL exists for is an illusion.
+func (s *tsdbStore) appendSnapshot(snap MetricsSnapshot) {
+	logAppendErr := func(seriesKey string, err error) {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "mole status: tsdb append %s: %v\n", seriesKey, err)
+		}
+	}
+
+	logAppendErr("cpu_percent", s.append("cpu_percent", nil, snap.Timestamp, snap.CPUPercent))
+	logAppendErr("mem_percent", s.append("mem_percent", nil, snap.Timestamp, snap.MemPercent))
+	logAppendErr("swap_percent", s.append("swap_percent", nil, snap.Timestamp, snap.SwapPercent))
+	for _, d := range snap.Disks {
+		seriesKey := seriesKeyFor("disk_used_percent", map[string]string{"device": d.Device})
+		logAppendErr(seriesKey, s.append("disk_used_percent", map[string]string{"device": d.Device}, snap.Timestamp, d.UsedPercent))
+	}
+}
+
+// append writes one WAL record and rotates the segment if it has grown past
+// WALSegmentSizeMB.
+func (s *tsdbStore) append(metric string, labels map[string]string, ts time.Time, value float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := seriesKeyFor(metric, labels)
+	rec := encodeWALRecord(key, ts, value)
+	if s.opts.CompressWAL {
+		rec = snappy.Encode(nil, rec)
+	}
+
+	var lenBuf [4]byte
+	binary.LittleEndian.PutUint32(lenBuf[:], uint32(len(rec)))
+	if _, err := s.walWriter.Write(lenBuf[:]); err != nil {
+		return err
+	}
+	if _, err := s.walWriter.Write(rec); err != nil {
+		return err
+	}
+	if err := s.walWriter.Flush(); err != nil {
+		return err
+	}
+	s.walSize += int64(len(rec)) + int64(len(lenBuf))
+
+	if s.walSize >= int64(s.opts.WALSegmentSizeMB)*1024*1024 {
+		if err := s.walSegment.Close(); err != nil {
+			return err
+		}
+		return s.openNextSegment()
+	}
+	return nil
+}
+
+// encodeWALRecord serializes one sample as [keyLen varint][key][ts unix nano][value bits].
+func encodeWALRecord(seriesKey string, ts time.Time, value float64) []byte {
+	buf := make([]byte, 0, len(seriesKey)+20)
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], uint64(len(seriesKey)))
+	buf = append(buf, tmp[:n]...)
+	buf = append(buf, seriesKey...)
+	n = binary.PutVarint(tmp[:], ts.UnixNano())
+	buf = append(buf, tmp[:n]...)
+	var vbuf [8]byte
+	binary.LittleEndian.PutUint64(vbuf[:], math.Float64bits(value))
+	buf = append(buf, vbuf[:]...)
+	return buf
+}
+
+func decodeWALRecord(rec []byte) (seriesKey string, ts time.Time, value float64, err error) {
+	keyLen, n := binary.Uvarint(rec)
+	if n <= 0 {
+		return "", time.Time{}, 0, fmt.Errorf("corrupt wal record: bad key length")
+	}
+	rec = rec[n:]
+	if uint64(len(rec)) < keyLen+8 {
+		return "", time.Time{}, 0, fmt.Errorf("corrupt wal record: truncated")
+	}
+	seriesKey = string(rec[:keyLen])
+	rec = rec[keyLen:]
+	nanos, n := binary.Varint(rec)
+	if n <= 0 {
+		return "", time.Time{}, 0, fmt.Errorf("corrupt wal record: bad timestamp")
+	}
+	rec = rec[n:]
+	value = math.Float64frombits(binary.LittleEndian.Uint64(rec))
+	return seriesKey, time.Unix(0, nanos), value, nil
+}
+
+// walSample is one decoded WAL entry, used while replaying or compacting.
+type walSample struct {
+	seriesKey string
+	ts        time.Time
+	value     float64
+}
+
+// readAllWAL replays every segment in walDir in order, decompressing records
+// as needed. This is how the store recovers after a crash: nothing is lost
+// until Compact() has folded a segment into a chunk.
+func (s *tsdbStore) readAllWAL() ([]walSample, error) {
+	entries, err := os.ReadDir(s.walDir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".wal") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var out []walSample
+	for _, name := range names {
+		samples, err := readWALSegment(filepath.Join(s.walDir, name), s.opts.CompressWAL)
+		if err != nil {
+			return nil, fmt.Errorf("replay %s: %w", name, err)
+		}
+		out = append(out, samples...)
+	}
+	return out, nil
+}
+
+func readWALSegment(path string, compressed bool) ([]walSample, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var out []walSample
+	for len(data) >= 4 {
+		l := binary.LittleEndian.Uint32(data[:4])
+		data = data[4:]
+		if uint64(len(data)) < uint64(l) {
+			break // trailing partial write from a crash mid-append; stop here
+		}
+		rec := data[:l]
+		data = data[l:]
+
+		if compressed {
+			rec, err = snappy.Decode(nil, rec)
+			if err != nil {
+				break
+			}
+		}
+		key, ts, value, err := decodeWALRecord(rec)
+		if err != nil {
+			break
+		}
+		out = append(out, walSample{seriesKey: key, ts: ts, value: value})
+	}
+	return out, nil
+}
+
+// Compact folds every WAL segment into per-series chunk files encoded with
+// delta-of-delta timestamps and XOR'd (Gorilla-style) float samples, then
+// starts a fresh, empty WAL.
+func (s *tsdbStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.walWriter.Flush(); err != nil {
+		return err
+	}
+
+	samples, err := s.readAllWAL()
+	if err != nil {
+		return err
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+
+	bySeries := make(map[string][]walSample)
+	for _, samp := range samples {
+		bySeries[samp.seriesKey] = append(bySeries[samp.seriesKey], samp)
+	}
+
+	for key, samps := range bySeries {
+		sort.Slice(samps, func(i, j int) bool { return samps[i].ts.Before(samps[j].ts) })
+		meta, err := writeChunk(s.chunksDir, key, samps)
+		if err != nil {
+			return fmt.Errorf("compact series %s: %w", key, err)
+		}
+		s.chunks[key] = append(s.chunks[key], meta)
+	}
+
+	// Retire the old segments and start clean: everything they held now
+	// lives in chunk files.
+	if err := s.walSegment.Close(); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(s.walDir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".wal") {
+			if err := os.Remove(filepath.Join(s.walDir, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return s.openNextSegment()
+}
+
+// ApplyRetention deletes any chunk entirely older than opts.Retention (or
+// the passed retention, if non-zero), and rewrites any chunk that straddles
+// the cutoff so it keeps only its in-range samples — a chunk's maxTime
+// alone can't gate deletion, since one compaction pass can bundle a stale
+// sample in with a recent one for the same series.
+func (s *tsdbStore) ApplyRetention(retention time.Duration) error {
+	if retention <= 0 {
+		retention = s.opts.Retention
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-retention)
+	for key, metas := range s.chunks {
+		kept := metas[:0]
+		for _, m := range metas {
+			switch {
+			case m.maxTime.Before(cutoff):
+				if err := os.Remove(m.path); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			case m.minTime.Before(cutoff):
+				rewritten, err := rewriteChunkAfter(s.chunksDir, key, m, cutoff)
+				if err != nil {
+					return fmt.Errorf("prune chunk %s: %w", m.path, err)
+				}
+				if rewritten != nil {
+					kept = append(kept, *rewritten)
+				}
+			default:
+				kept = append(kept, m)
+			}
+		}
+		s.chunks[key] = kept
+	}
+	return nil
+}
+
+// rewriteChunkAfter reads the chunk at old, keeps only samples at or after
+// cutoff, and replaces it with a freshly written chunk covering just those
+// samples. It returns nil if every sample in old was before cutoff, in
+// which case the caller should drop the chunk entirely.
+func rewriteChunkAfter(dir, seriesKey string, old chunkMeta, cutoff time.Time) (*chunkMeta, error) {
+	samps, _, err := readChunk(old.path)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make([]walSample, 0, len(samps))
+	for _, samp := range samps {
+		if !samp.Timestamp.Before(cutoff) {
+			kept = append(kept, walSample{seriesKey: seriesKey, ts: samp.Timestamp, value: samp.Value})
+		}
+	}
+
+	if err := os.Remove(old.path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(kept) == 0 {
+		return nil, nil
+	}
+
+	meta, err := writeChunk(dir, seriesKey, kept)
+	if err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// Query returns every sample recorded for seriesKey within [from, to],
+// merging compacted chunks with whatever is still sitting in the WAL.
+func (s *tsdbStore) Query(seriesKey string, from, to time.Time) ([]Sample, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []Sample
+	for _, m := range s.chunks[seriesKey] {
+		if m.maxTime.Before(from) || m.minTime.After(to) {
+			continue
+		}
+		samps, _, err := readChunk(m.path)
+		if err != nil {
+			return nil, fmt.Errorf("read chunk %s: %w", m.path, err)
+		}
+		for _, samp := range samps {
+			if !samp.Timestamp.Before(from) && !samp.Timestamp.After(to) {
+				out = append(out, samp)
+			}
+		}
+	}
+
+	walSamples, err := s.readAllWAL()
+	if err != nil {
+		return nil, err
+	}
+	for _, samp := range walSamples {
+		if samp.seriesKey != seriesKey {
+			continue
+		}
+		if !samp.ts.Before(from) && !samp.ts.After(to) {
+			out = append(out, Sample{Timestamp: samp.ts, Value: samp.value})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Timestamp.Before(out[j].Timestamp) })
+	return out, nil
+}
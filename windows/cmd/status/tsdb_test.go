@@ -0,0 +1,179 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T, compress bool) *tsdbStore {
+	t.Helper()
+	dir := t.TempDir()
+	s, err := newTSDBStore(CollectorOptions{
+		StoreDir:         dir,
+		WALSegmentSizeMB: 16,
+		Retention:        time.Hour,
+		CompressWAL:      compress,
+	})
+	if err != nil {
+		t.Fatalf("newTSDBStore: %v", err)
+	}
+	return s
+}
+
+func TestWALReplayAfterCrash(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		dir := t.TempDir()
+		opts := CollectorOptions{StoreDir: dir, WALSegmentSizeMB: 16, CompressWAL: compress}
+
+		s, err := newTSDBStore(opts)
+		if err != nil {
+			t.Fatalf("newTSDBStore: %v", err)
+		}
+		for i := 0; i < 5; i++ {
+			if err := s.append("cpu_percent", nil, base.Add(time.Duration(i)*time.Second), float64(10+i)); err != nil {
+				t.Fatalf("append: %v", err)
+			}
+		}
+		// Simulate a crash: no Close(), no Compact(), just a fresh store
+		// pointed at the same directory.
+		s2, err := newTSDBStore(opts)
+		if err != nil {
+			t.Fatalf("reopen store: %v", err)
+		}
+
+		got, err := s2.Query("cpu_percent", base.Add(-time.Minute), base.Add(time.Minute))
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		if len(got) != 5 {
+			t.Fatalf("compress=%v: got %d samples after replay, want 5", compress, len(got))
+		}
+		for i, samp := range got {
+			if samp.Value != float64(10+i) {
+				t.Errorf("compress=%v: sample %d = %v, want %v", compress, i, samp.Value, float64(10+i))
+			}
+		}
+	}
+}
+
+func TestCompactionCorrectness(t *testing.T) {
+	s := newTestStore(t, false)
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	want := make([]float64, 50)
+	for i := 0; i < 50; i++ {
+		v := 40 + 10*float64(i%3) // repeats so some XOR deltas are zero
+		want[i] = v
+		if err := s.append("mem_percent", nil, base.Add(time.Duration(i)*time.Second), v); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	// The WAL should now be empty; all the data must be served from chunks.
+	entries, err := os.ReadDir(s.walDir)
+	if err != nil {
+		t.Fatalf("ReadDir wal: %v", err)
+	}
+	for _, e := range entries {
+		if fi, _ := e.Info(); fi != nil && fi.Size() > 0 {
+			t.Errorf("expected empty WAL after compaction, found non-empty %s", e.Name())
+		}
+	}
+
+	got, err := s.Query("mem_percent", base.Add(-time.Minute), base.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d samples after compaction, want %d", len(got), len(want))
+	}
+	for i, samp := range got {
+		if samp.Value != want[i] {
+			t.Errorf("sample %d = %v, want %v", i, samp.Value, want[i])
+		}
+	}
+}
+
+// TestCompactionCorrectnessDodBoundary exercises delta-of-delta values that
+// land exactly on the 7/9/12-bit width boundaries (64/256/2048ns), which
+// TestCompactionCorrectness's constant 1-second spacing never does.
+func TestCompactionCorrectnessDodBoundary(t *testing.T) {
+	s := newTestStore(t, false)
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Deltas: 100, 100 (dod=0), 164 (dod=64), 164 (dod=0), 420 (dod=256),
+	// 420 (dod=0), 2468 (dod=2048).
+	deltas := []int64{100, 100, 164, 164, 420, 420, 2468}
+	ts := base
+	wantTS := []time.Time{ts}
+	for _, d := range deltas {
+		ts = ts.Add(time.Duration(d))
+		wantTS = append(wantTS, ts)
+	}
+	want := make([]float64, len(wantTS))
+	for i, at := range wantTS {
+		v := float64(i)
+		want[i] = v
+		if err := s.append("cpu_percent", nil, at, v); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	got, err := s.Query("cpu_percent", base.Add(-time.Minute), wantTS[len(wantTS)-1].Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d samples after compaction, want %d", len(got), len(want))
+	}
+	for i, samp := range got {
+		if samp.Value != want[i] {
+			t.Errorf("sample %d value = %v, want %v", i, samp.Value, want[i])
+		}
+		if !samp.Timestamp.Equal(wantTS[i]) {
+			t.Errorf("sample %d timestamp = %v, want %v", i, samp.Timestamp, wantTS[i])
+		}
+	}
+}
+
+func TestRetentionPruning(t *testing.T) {
+	s := newTestStore(t, false)
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := time.Now().Add(-time.Minute)
+
+	if err := s.append("cpu_percent", nil, old, 5); err != nil {
+		t.Fatalf("append old: %v", err)
+	}
+	if err := s.append("cpu_percent", nil, recent, 95); err != nil {
+		t.Fatalf("append recent: %v", err)
+	}
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	if err := s.ApplyRetention(24 * time.Hour); err != nil {
+		t.Fatalf("ApplyRetention: %v", err)
+	}
+
+	got, err := s.Query("cpu_percent", old.Add(-time.Hour), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d samples after retention, want 1 (only the recent one)", len(got))
+	}
+	if got[0].Value != 95 {
+		t.Errorf("surviving sample = %v, want 95", got[0].Value)
+	}
+}